@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
 	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/timeconversion"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
 	"gonum.org/v1/plot/vg"
 )
 
@@ -17,38 +25,174 @@ const (
 	fftSpectrumPath  = outputDir + "/fft_spectrum.png"
 )
 
+// hostOnlyFlags are the flags configuring the connection to host, which cannot be used
+// when records are read from dir.
+var hostOnlyFlags = []string{
+	"auth",
+	"credentials-file",
+	"netrc",
+	"login-url",
+	"ca-file",
+	"pin",
+	"client-cert",
+	"client-key",
+	"insecure",
+	"cache",
+}
+
 func main() {
 	host := flag.String("host", "", "Host URL")
+	archiveDir := flag.String(
+		"dir",
+		"",
+		"Directory of archived records to read instead of connecting to a host. "+
+			"The authentication, TLS and cache flags only apply with host",
+	)
 	machine := flag.String("machine", "", "Machine name")
 	point := flag.String("point", "", "Point name")
 	pmode := flag.String("pmode", "", "Pmode value")
-	dateTime := flag.String("datetime", "", "Date and time")
+	dateTime := flag.String(
+		"datetime",
+		"",
+		"Date and time: RFC 3339, ISO 8601 without time zone, "+
+			"a Unix timestamp in seconds or milliseconds, or now[+-]duration",
+	)
+	timezone := flag.String(
+		"timezone",
+		"UTC",
+		"Time zone of dates without time zone, such as Europe/Madrid or Local",
+	)
+	nearest := flag.String(
+		"nearest",
+		"",
+		"Fetch the records nearest to datetime instead of the exact ones: around, before or after",
+	)
+	tolerance := flag.Duration(
+		"tolerance",
+		0,
+		"Largest distance between datetime and the nearest records, or 0 for any distance",
+	)
+	authMethod := flag.String("auth", "basic", "Authentication method: basic, bearer or session")
+	credentialsFile := flag.String(
+		"credentials-file",
+		"",
+		"File holding user:password, or the token for bearer authentication",
+	)
+	useNetrc := flag.Bool("netrc", false, "Read the user and password for host from ~/.netrc")
+	loginURL := flag.String(
+		"login-url",
+		"login",
+		"Login endpoint for session authentication: a URL, or a path relative to host",
+	)
+	caFile := flag.String("ca-file", "", "PEM file of CA certificates to trust for host")
+	pin := flag.String(
+		"pin",
+		"",
+		"SHA-256 fingerprint of the only certificate accepted from host, e.g. AB:CD:...",
+	)
+	clientCert := flag.String("client-cert", "", "PEM certificate file for mutual TLS")
+	clientKey := flag.String("client-key", "", "PEM private key file for mutual TLS")
+	insecure := flag.Bool(
+		"insecure",
+		false,
+		"Skip TLS certificate verification (INSECURE, for testing only)",
+	)
+	verbose := flag.Bool("verbose", false, "Log every request to the standard error")
+	cacheDir := flag.String(
+		"cache",
+		"",
+		"Directory to cache downloaded records in, or empty to disable caching. "+
+			"Concurrent runs against the same host must not share it",
+	)
+	windowName := flag.String(
+		"window",
+		"hann",
+		"Window of the FFT spectrum: rectangular, hann, hamming, flattop, blackmanharris or kaiser",
+	)
+	kaiserBeta := flag.Float64("kaiser-beta", 8.6, "Shape parameter of the Kaiser window")
+	energyCorrection := flag.Bool(
+		"energy-correction",
+		false,
+		"Correct the FFT spectrum for the energy of the window instead of its amplitude",
+	)
 	flag.Parse()
 
-	if *host == "" || *machine == "" || *point == "" || *pmode == "" || *dateTime == "" {
-		fmt.Println("All parameters (host, machine, point, pmode, datetime) are required.")
+	if (*host == "") == (*archiveDir == "") || *machine == "" || *point == "" || *pmode == "" ||
+		*dateTime == "" {
+		fmt.Println(
+			"Either host or dir, and all of machine, point, pmode and datetime are required.",
+		)
 		flag.Usage()
 		return
 	}
 
-	user := os.Getenv("T8_CLIENT_USER")
-	password := os.Getenv("T8_CLIENT_PASSWORD")
+	if *archiveDir != "" {
+		if names := setFlags(hostOnlyFlags); len(names) > 0 {
+			fmt.Printf("Flags %s only apply with host, not with dir.\n", strings.Join(names, ", "))
+			flag.Usage()
+			return
+		}
+	}
+
+	location, err := time.LoadLocation(*timezone)
+	if err != nil {
+		fmt.Println("Error loading time zone:", err)
+		return
+	}
+
+	acquired, err := timeconversion.ParseTime(*dateTime, location, time.Now())
+	if err != nil {
+		fmt.Println("Error parsing date and time:", err)
+		return
+	}
 
-	urlParams := datafetcher.NewPmodeUrlTimeParams(
+	urlParams := datafetcher.NewPmodeUrlTimeParamsAt(*machine, *point, *pmode, acquired)
+
+	var nearestOpts *datafetcher.NearestOptions
+	if *nearest != "" {
+		direction, err := datafetcher.ParseDirection(*nearest)
+		if err != nil {
+			fmt.Println("Error parsing nearest direction:", err)
+			return
+		}
+		nearestOpts = &datafetcher.NearestOptions{Direction: direction, Tolerance: *tolerance}
+	}
+
+	spectrumOpts := spectra.DefaultSpectrumOptions()
+	spectrumOpts.Window, err = waveforms.ParseWindow(*windowName, *kaiserBeta)
+	if err != nil {
+		fmt.Println("Error parsing window:", err)
+		return
+	}
+	if *energyCorrection {
+		spectrumOpts.Correction = spectra.EnergyCorrection
+	}
+
+	auth, err := newAuthenticator(*authMethod, *credentialsFile, *useNetrc, *host, *loginURL)
+	if err != nil {
+		fmt.Println("Error configuring authentication:", err)
+		return
+	}
+
+	tlsOpts, err := tlsOptions(*caFile, *pin, *clientCert, *clientKey, *insecure)
+	if err != nil {
+		fmt.Println("Error configuring TLS:", err)
+		return
+	}
+
+	fetcher, err := newFetcher(
 		*host,
-		*machine,
-		*point,
-		*pmode,
-		*dateTime,
-		user,
-		password,
+		*archiveDir,
+		*cacheDir,
+		append(tlsOpts, datafetcher.WithAuthenticator(auth), loggerOption(*verbose))...,
 	)
-
-	// Updated to use the HttpDataFetcher implementation
-	fetcher := datafetcher.HttpDataFetcher{}
+	if err != nil {
+		fmt.Println("Error creating fetcher:", err)
+		return
+	}
 
 	// Waveform
-	waveform, err := fetcher.GetWaveform(urlParams)
+	waveform, err := getWaveform(fetcher, urlParams, nearestOpts)
 	if err != nil {
 		fmt.Println("Error getting waveform:", err)
 		return
@@ -74,13 +218,13 @@ func main() {
 	fmt.Println("Waveform plot saved to", waveformPlotPath)
 
 	// T8 Spectrum
-	t8_spectrum, fmin, fmax, err := fetcher.GetSpectrum(urlParams)
+	t8_spectrum, err := getSpectrum(fetcher, urlParams, nearestOpts)
 	if err != nil {
 		fmt.Println("Error getting T8 spectrum:", err)
 		return
 	}
 
-	plot, err = t8_spectrum.Plot(fmin, fmax)
+	plot, err = t8_spectrum.Plot()
 	if err != nil {
 		fmt.Println("Error plotting T8 spectrum:", err)
 		return
@@ -94,11 +238,14 @@ func main() {
 	fmt.Println("T8 spectrum plot saved to", spectrumPlotPath)
 
 	// FFT Spectrum
-	waveform.Preprocess()
-
-	spectrum := spectra.SpectrumFromWaveform(waveform, fmin, fmax)
+	spectrum := spectra.SpectrumFromWaveformWithOptions(
+		waveform,
+		t8_spectrum.Fmin,
+		t8_spectrum.Fmax,
+		spectrumOpts,
+	)
 
-	plot, err = spectrum.Plot(fmin, fmax)
+	plot, err = spectrum.Plot()
 	if err != nil {
 		fmt.Println("Error plotting FFT spectrum:", err)
 		return
@@ -110,3 +257,168 @@ func main() {
 	}
 	fmt.Println("FFT spectrum plot saved to", fftSpectrumPath)
 }
+
+// getWaveform retrieves the waveform acquired at the time of urlParams or, if
+// nearestOpts is not nil, the nearest one, reporting its acquisition time.
+func getWaveform(
+	fetcher datafetcher.RecordSource,
+	urlParams datafetcher.PmodeUrlTimeParams,
+	nearestOpts *datafetcher.NearestOptions,
+) (waveforms.Waveform, error) {
+	if nearestOpts == nil {
+		return fetcher.GetWaveform(urlParams)
+	}
+
+	waveform, err := datafetcher.GetNearestWaveform(
+		context.Background(),
+		fetcher,
+		urlParams,
+		*nearestOpts,
+	)
+	if err != nil {
+		return waveforms.Waveform{}, err
+	}
+
+	fmt.Println("Using waveform acquired at", waveform.Time.Format(time.RFC3339))
+	return waveform, nil
+}
+
+// getSpectrum retrieves the spectrum acquired at the time of urlParams or, if
+// nearestOpts is not nil, the nearest one, reporting its acquisition time.
+func getSpectrum(
+	fetcher datafetcher.RecordSource,
+	urlParams datafetcher.PmodeUrlTimeParams,
+	nearestOpts *datafetcher.NearestOptions,
+) (spectra.Spectrum, error) {
+	if nearestOpts == nil {
+		return fetcher.GetSpectrum(urlParams)
+	}
+
+	spectrum, err := datafetcher.GetNearestSpectrum(
+		context.Background(),
+		fetcher,
+		urlParams,
+		*nearestOpts,
+	)
+	if err != nil {
+		return spectra.Spectrum{}, err
+	}
+
+	fmt.Println("Using T8 spectrum acquired at", spectrum.Time.Format(time.RFC3339))
+	return spectrum, nil
+}
+
+// newAuthenticator creates the Authenticator for the given method. The credentials are
+// read from credentialsFile if it is set, from the netrc file if useNetrc is set, or
+// otherwise from the T8_CLIENT_USER and T8_CLIENT_PASSWORD environment variables, or
+// T8_CLIENT_TOKEN for bearer authentication. A loginURL without scheme is relative to
+// host.
+func newAuthenticator(
+	method, credentialsFile string,
+	useNetrc bool,
+	host, loginURL string,
+) (datafetcher.Authenticator, error) {
+	if method == "bearer" {
+		if useNetrc {
+			return nil, errors.New("netrc does not hold tokens for bearer authentication")
+		}
+		if credentialsFile != "" {
+			return datafetcher.BearerAuth(datafetcher.FileToken(credentialsFile)), nil
+		}
+		return datafetcher.BearerAuth(datafetcher.EnvToken("T8_CLIENT_TOKEN")), nil
+	}
+
+	credentials := datafetcher.EnvCredentials("T8_CLIENT_USER", "T8_CLIENT_PASSWORD")
+	switch {
+	case credentialsFile != "" && useNetrc:
+		return nil, errors.New("credentials-file and netrc are mutually exclusive")
+	case credentialsFile != "":
+		credentials = datafetcher.FileCredentials(credentialsFile)
+	case useNetrc:
+		credentials = datafetcher.NetrcCredentials("")
+	}
+
+	switch method {
+	case "basic":
+		return datafetcher.BasicAuth(credentials), nil
+	case "session":
+		if !strings.Contains(loginURL, "://") {
+			loginURL = strings.TrimRight(host, "/") + "/" + strings.TrimLeft(loginURL, "/")
+		}
+		return datafetcher.SessionAuth(
+			datafetcher.SessionLogin{URL: loginURL, Credentials: credentials},
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q", method)
+	}
+}
+
+// tlsOptions returns the client options for the TLS flags.
+func tlsOptions(
+	caFile, pin, clientCert, clientKey string,
+	insecure bool,
+) ([]datafetcher.ClientOption, error) {
+	var opts []datafetcher.ClientOption
+	if caFile != "" {
+		opts = append(opts, datafetcher.WithCAFile(caFile))
+	}
+	if pin != "" {
+		opts = append(opts, datafetcher.WithPinnedCertificate(pin))
+	}
+	if (clientCert == "") != (clientKey == "") {
+		return nil, errors.New("client-cert and client-key must be given together")
+	}
+	if clientCert != "" {
+		opts = append(opts, datafetcher.WithClientCertificateFiles(clientCert, clientKey))
+	}
+	if insecure {
+		opts = append(opts, datafetcher.WithInsecureSkipVerify())
+	}
+	return opts, nil
+}
+
+// setFlags returns the names, among names, of the flags set on the command line.
+func setFlags(names []string) []string {
+	var set []string
+	flag.Visit(func(f *flag.Flag) {
+		if slices.Contains(names, f.Name) {
+			set = append(set, "--"+f.Name)
+		}
+	})
+	return set
+}
+
+// loggerOption returns the client option logging requests to the standard error, every
+// request if verbose is set, or only failures otherwise.
+func loggerOption(verbose bool) datafetcher.ClientOption {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	return datafetcher.WithLogger(slog.New(handler))
+}
+
+// newFetcher creates the RecordSource the records are read with: a FileDataFetcher if
+// archiveDir is set, or otherwise an HttpDataFetcher for host configured with opts,
+// cached in cacheDir unless it is empty.
+func newFetcher(
+	host, archiveDir, cacheDir string,
+	opts ...datafetcher.ClientOption,
+) (datafetcher.RecordSource, error) {
+	if archiveDir != "" {
+		return datafetcher.NewFileDataFetcher(archiveDir)
+	}
+
+	client, err := datafetcher.NewClient(host, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	if cacheDir == "" {
+		return fetcher, nil
+	}
+
+	return datafetcher.NewCachingDataFetcher(fetcher, cacheDir)
+}
@@ -0,0 +1,73 @@
+// Package codec decodes and encodes the sample payloads of T8 devices.
+//
+// A T8 payload is a base64-encoded string holding a sequence of samples of a fixed
+// width, in little-endian byte order and, usually, zlib-compressed. Integer samples are
+// meant to be multiplied by a scale factor sent along with the payload. The most common
+// format, zint, holds zlib-compressed int16 samples.
+package codec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncompleteSample is returned when the decoded data does not hold a whole number of
+// samples, e.g. an odd number of bytes for int16 samples.
+var ErrIncompleteSample = errors.New("data length is not a multiple of the sample size")
+
+// SampleType is the type of the samples of a payload.
+type SampleType int
+
+const (
+	// Int16 samples are 16-bit signed integers.
+	Int16 SampleType = iota
+	// Int32 samples are 32-bit signed integers.
+	Int32
+	// Float32 samples are IEEE 754 single-precision floating-point numbers.
+	Float32
+)
+
+// String returns a lower-case name for the sample type.
+func (t SampleType) String() string {
+	switch t {
+	case Int16:
+		return "int16"
+	case Int32:
+		return "int32"
+	case Float32:
+		return "float32"
+	default:
+		return fmt.Sprintf("SampleType(%d)", int(t))
+	}
+}
+
+// size returns the size of a sample, in bytes, or an error if the type is unknown.
+func (t SampleType) size() (int, error) {
+	switch t {
+	case Int16:
+		return 2, nil
+	case Int32, Float32:
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("unsupported sample type %s", t)
+	}
+}
+
+// Format describes how the samples of a payload are encoded.
+type Format struct {
+	// Sample is the type of the samples.
+	Sample SampleType
+	// Compressed tells whether the samples are zlib-compressed before being base64-encoded.
+	Compressed bool
+}
+
+// Zint is the format of most T8 payloads: zlib-compressed int16 samples.
+var Zint = Format{Sample: Int16, Compressed: true}
+
+// String returns a short description of the format, such as "zlib int16".
+func (f Format) String() string {
+	if f.Compressed {
+		return "zlib " + f.Sample.String()
+	}
+	return f.Sample.String()
+}
@@ -0,0 +1,116 @@
+package codec
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// ZintToFloat decodes a base64-encoded, zlib-compressed string into a slice of float64 values.
+//
+// The function performs the following steps:
+// 1. Decodes the input string from base64 encoding.
+// 2. Decompresses the decoded data using zlib.
+// 3. Reads the decompressed data as a sequence of int16 values in little-endian byte order.
+//
+// Parameters:
+// - raw: A base64-encoded string containing zlib-compressed binary data.
+//
+// Returns:
+// - A slice of float64 values decoded from the input string.
+// - An error if any step of the decoding or decompression process fails, matching
+// ErrIncompleteSample if the decompressed data has an odd length.
+func ZintToFloat(raw string) ([]float64, error) {
+	return Decode(strings.NewReader(raw), Zint)
+}
+
+// ZintReaderToFloat decodes base64-encoded, zlib-compressed int16 data read from r into
+// a slice of float64 values, like ZintToFloat. It is a shorthand for Decode with the
+// Zint format.
+//
+// Parameters:
+// - r: A reader yielding base64-encoded text containing zlib-compressed binary data.
+//
+// Returns:
+// - A slice of float64 values decoded from the input.
+// - An error if reading from r or any step of the decoding or decompression process fails.
+func ZintReaderToFloat(r io.Reader) ([]float64, error) {
+	return Decode(r, Zint)
+}
+
+// DecodeString decodes a base64-encoded payload in the given format into a slice of
+// float64 values. See Decode.
+func DecodeString(raw string, format Format) ([]float64, error) {
+	return Decode(strings.NewReader(raw), format)
+}
+
+// Decode decodes a base64-encoded payload in the given format, read from r, into a slice
+// of float64 values. The data is streamed through the base64 and zlib decoders, so the
+// encoded data is never held in memory. Only the decoded samples, at most half the size
+// of the result, are buffered, so that the result can be allocated at its exact size.
+//
+// Parameters:
+// - r: A reader yielding the base64-encoded payload.
+// - format: The format of the payload.
+//
+// Returns:
+// - A slice of float64 values decoded from the input, not scaled by any factor.
+// - An error if reading from r or any step of the decoding or decompression process fails,
+// matching ErrIncompleteSample if the data does not hold a whole number of samples.
+func Decode(r io.Reader, format Format) ([]float64, error) {
+	size, err := format.Sample.size()
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = base64.NewDecoder(base64.StdEncoding, r)
+	var zr io.ReadCloser
+	if format.Compressed {
+		zr, err = zlib.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		src = zr
+	}
+
+	var decoded bytes.Buffer
+	if _, err := io.Copy(&decoded, src); err != nil {
+		return nil, err
+	}
+
+	if zr != nil {
+		if err := zr.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	data := decoded.Bytes()
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf(
+			"%w: %d bytes of %s samples",
+			ErrIncompleteSample,
+			len(data),
+			format.Sample,
+		)
+	}
+
+	array := make([]float64, len(data)/size)
+	for i := range array {
+		sample := data[i*size:]
+		switch format.Sample {
+		case Int16:
+			array[i] = float64(int16(binary.LittleEndian.Uint16(sample)))
+		case Int32:
+			array[i] = float64(int32(binary.LittleEndian.Uint32(sample)))
+		case Float32:
+			array[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(sample)))
+		}
+	}
+
+	return array, nil
+}
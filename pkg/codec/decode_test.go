@@ -0,0 +1,215 @@
+package codec_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/codec"
+)
+
+func TestZintToFloat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []float64
+		mustFail bool
+	}{
+		{
+			name:     "Valid Base64 String",
+			input:    "eJxjZPj//389QwMAEP4D/g==",
+			expected: []float64{1, -1, 32767, -32768},
+		},
+		{
+			name:     "Invalid Base64 String",
+			input:    "invalid_base64",
+			expected: nil,
+			mustFail: true,
+		},
+		{
+			name:     "Empty Base64 String",
+			input:    "",
+			expected: nil,
+			mustFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := codec.ZintToFloat(tc.input)
+			if tc.mustFail {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+				}
+				if len(result) != len(tc.expected) {
+					t.Errorf("Expected length %d but got %d", len(tc.expected), len(result))
+				}
+				for i, v := range result {
+					if v != tc.expected[i] {
+						t.Errorf("Expected %f but got %f at index %d", tc.expected[i], v, i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestZintReaderToFloat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    []float64
+		mustFail    bool
+		expectedErr error
+	}{
+		{
+			name:     "Valid Base64 String",
+			input:    "eJxjZPj//389QwMAEP4D/g==",
+			expected: []float64{1, -1, 32767, -32768},
+		},
+		{
+			name:        "Trailing Odd Byte",
+			input:       "eJxjZPj//389QwMrABUBBAM=",
+			expected:    nil,
+			mustFail:    true,
+			expectedErr: codec.ErrIncompleteSample,
+		},
+		{
+			name:     "Truncated Zlib Stream",
+			input:    "eJxjZPj//389QwMAEA==",
+			expected: nil,
+			mustFail: true,
+		},
+		{
+			name:     "Invalid Base64 String",
+			input:    "invalid_base64",
+			expected: nil,
+			mustFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// iotest.OneByteReader forces samples to be split across reads.
+			result, err := codec.ZintReaderToFloat(
+				iotest.OneByteReader(strings.NewReader(tc.input)),
+			)
+			if tc.mustFail {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected %v but got: %v", tc.expectedErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+				}
+				if !reflect.DeepEqual(result, tc.expected) {
+					t.Errorf("Expected %v but got %v", tc.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFormats(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		format      codec.Format
+		expected    []float64
+		expectedErr error
+	}{
+		{
+			name:     "Uncompressed Int16",
+			input:    "AQD///9/AIA=",
+			format:   codec.Format{Sample: codec.Int16},
+			expected: []float64{1, -1, 32767, -32768},
+		},
+		{
+			name:     "Uncompressed Int32",
+			input:    "AQAAAP/////+//9/",
+			format:   codec.Format{Sample: codec.Int32},
+			expected: []float64{1, -1, 2147483646},
+		},
+		{
+			name:     "Compressed Int32",
+			input:    "eJxjZGBg+A8E//7/rwcAI3AHeQ==",
+			format:   codec.Format{Sample: codec.Int32, Compressed: true},
+			expected: []float64{1, -1, 2147483646},
+		},
+		{
+			name:     "Uncompressed Float32",
+			input:    "AADAPwAAIMEAAAAA",
+			format:   codec.Format{Sample: codec.Float32},
+			expected: []float64{1.5, -10, 0},
+		},
+		{
+			name:     "Empty Uncompressed",
+			input:    "",
+			format:   codec.Format{Sample: codec.Float32},
+			expected: []float64{},
+		},
+		{
+			name:        "Odd Length Int16",
+			input:       "AQD/",
+			format:      codec.Format{Sample: codec.Int16},
+			expectedErr: codec.ErrIncompleteSample,
+		},
+		{
+			name:        "Incomplete Int32",
+			input:       "AQAAAP//",
+			format:      codec.Format{Sample: codec.Int32},
+			expectedErr: codec.ErrIncompleteSample,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := codec.DecodeString(tc.input, tc.format)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected %v but got: %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDecodeUnsupportedFormat(t *testing.T) {
+	if _, err := codec.DecodeString("", codec.Format{Sample: codec.SampleType(42)}); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	testCases := []struct {
+		format   codec.Format
+		expected string
+	}{
+		{format: codec.Zint, expected: "zlib int16"},
+		{format: codec.Format{Sample: codec.Int32}, expected: "int32"},
+		{format: codec.Format{Sample: codec.Float32, Compressed: true}, expected: "zlib float32"},
+		{format: codec.Format{Sample: codec.SampleType(42)}, expected: "SampleType(42)"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.format.String(); got != tc.expected {
+			t.Errorf("Expected %q but got %q", tc.expected, got)
+		}
+	}
+}
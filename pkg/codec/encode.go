@@ -0,0 +1,117 @@
+package codec
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// FloatToZint encodes a slice of float64 values into a base64-encoded, zlib-compressed
+// string, the inverse of ZintToFloat.
+//
+// The function performs the following steps:
+// 1. Chooses the scale factor that maps the largest magnitude in values to 32767.
+// 2. Quantizes every value, divided by the factor, to the nearest int16.
+// 3. Writes the int16 values in little-endian byte order.
+// 4. Compresses the data using zlib and encodes it in base64.
+//
+// Decoding the result with ZintToFloat and multiplying by the factor yields values
+// within half a factor of the original ones.
+//
+// Parameters:
+// - values: The values to encode. They must be finite.
+//
+// Returns:
+// - A base64-encoded string containing the zlib-compressed int16 values.
+// - The scale factor, which is 1 if every value is zero or too small to be scaled.
+// - An error if any value is NaN or infinite.
+func FloatToZint(values []float64) (string, float64, error) {
+	return Encode(values, Zint)
+}
+
+// Encode encodes a slice of float64 values into a base64-encoded payload in the given
+// format, the inverse of Decode.
+//
+// Integer samples are quantized with the scale factor that maps the largest magnitude
+// in values to the largest value of the sample type, so that decoding the payload and
+// multiplying by the factor yields values within half a factor of the original ones.
+// Float32 samples are not scaled, and are only rounded to single precision.
+//
+// Parameters:
+// - values: The values to encode. They must be finite, and within the float32 range for
+// Float32 samples.
+// - format: The format of the payload.
+//
+// Returns:
+// - The base64-encoded payload.
+// - The scale factor, which is 1 for Float32 samples or if every value is zero or too
+// small to be scaled.
+// - An error if any value cannot be encoded or the format is not supported.
+func Encode(values []float64, format Format) (string, float64, error) {
+	size, err := format.Sample.size()
+	if err != nil {
+		return "", 0, err
+	}
+
+	maxAbs := 0.0
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", 0, errors.New("cannot encode non-finite value")
+		}
+		maxAbs = max(maxAbs, math.Abs(v))
+	}
+
+	factor := 1.0
+	switch format.Sample {
+	case Int16:
+		if maxAbs > 0 {
+			factor = maxAbs / math.MaxInt16
+		}
+	case Int32:
+		if maxAbs > 0 {
+			factor = maxAbs / math.MaxInt32
+		}
+	case Float32:
+		if maxAbs > math.MaxFloat32 {
+			return "", 0, fmt.Errorf("value %v out of float32 range", maxAbs)
+		}
+	}
+	// Subnormal values too small to be scaled are encoded as zeros, rather than divided
+	// by a factor that underflowed to zero.
+	if factor == 0 {
+		factor = 1
+	}
+
+	raw := make([]byte, size*len(values))
+	for i, v := range values {
+		sample := raw[i*size:]
+		switch format.Sample {
+		case Int16:
+			quantized := min(max(math.Round(v/factor), math.MinInt16), math.MaxInt16)
+			binary.LittleEndian.PutUint16(sample, uint16(int16(quantized)))
+		case Int32:
+			quantized := min(max(math.Round(v/factor), math.MinInt32), math.MaxInt32)
+			binary.LittleEndian.PutUint32(sample, uint32(int32(quantized)))
+		case Float32:
+			binary.LittleEndian.PutUint32(sample, math.Float32bits(float32(v)))
+		}
+	}
+
+	if format.Compressed {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(raw); err != nil {
+			return "", 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return "", 0, err
+		}
+		raw = compressed.Bytes()
+	}
+
+	return base64.StdEncoding.EncodeToString(raw), factor, nil
+}
@@ -0,0 +1,258 @@
+package codec_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/codec"
+)
+
+// TestFloatToZint tests encoding values with known encodings and factors.
+func TestFloatToZint(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          []float64
+		expected       []float64
+		expectedFactor float64
+		mustFail       bool
+	}{
+		{
+			name:           "Integer Values",
+			input:          []float64{1, -1, 32767, -32767},
+			expected:       []float64{1, -1, 32767, -32767},
+			expectedFactor: 1,
+		},
+		{
+			name:           "Scaled Values",
+			input:          []float64{0.5, -0.25, 0},
+			expected:       []float64{32767, -16384, 0},
+			expectedFactor: 0.5 / 32767,
+		},
+		{
+			name:           "All Zeros",
+			input:          []float64{0, 0},
+			expected:       []float64{0, 0},
+			expectedFactor: 1,
+		},
+		{
+			name:           "Subnormal Values",
+			input:          []float64{math.SmallestNonzeroFloat64, 0, -math.SmallestNonzeroFloat64},
+			expected:       []float64{0, 0, 0},
+			expectedFactor: 1,
+		},
+		{
+			name:           "Empty",
+			input:          []float64{},
+			expected:       []float64{},
+			expectedFactor: 1,
+		},
+		{
+			name:     "NaN",
+			input:    []float64{1, math.NaN()},
+			mustFail: true,
+		},
+		{
+			name:     "Infinity",
+			input:    []float64{math.Inf(-1)},
+			mustFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, factor, err := codec.FloatToZint(tc.input)
+			if tc.mustFail {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if factor != tc.expectedFactor {
+				t.Errorf("Expected factor %v but got %v", tc.expectedFactor, factor)
+			}
+
+			result, err := codec.ZintToFloat(raw)
+			if err != nil {
+				t.Fatalf("Expected no error decoding but got: %v", err)
+			}
+			if len(result) != len(tc.expected) {
+				t.Fatalf("Expected length %d but got %d", len(tc.expected), len(result))
+			}
+			for i, v := range result {
+				if v != tc.expected[i] {
+					t.Errorf("Expected %f but got %f at index %d", tc.expected[i], v, i)
+				}
+			}
+		})
+	}
+}
+
+// TestFloatToZintRoundTrip tests, on random inputs of varying lengths and scales, that
+// decoding an encoded slice and applying the factor restores every value within half
+// a quantization step, and that the largest magnitude uses the whole int16 range.
+func TestFloatToZintRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for iteration := range 500 {
+		values := make([]float64, rng.IntN(4096))
+		scale := math.Pow(10, float64(rng.IntN(25)-12))
+		for i := range values {
+			values[i] = (2*rng.Float64() - 1) * scale
+		}
+
+		raw, factor, err := codec.FloatToZint(values)
+		if err != nil {
+			t.Fatalf("iteration %d: expected no error but got: %v", iteration, err)
+		}
+
+		decoded, err := codec.ZintToFloat(raw)
+		if err != nil {
+			t.Fatalf("iteration %d: expected no error decoding but got: %v", iteration, err)
+		}
+		if len(decoded) != len(values) {
+			t.Fatalf(
+				"iteration %d: expected length %d but got %d",
+				iteration,
+				len(values),
+				len(decoded),
+			)
+		}
+
+		maxQuantized := 0.0
+		for i, v := range decoded {
+			maxQuantized = max(maxQuantized, math.Abs(v))
+			// Allow for the rounding of the float64 arithmetic itself.
+			if math.Abs(v*factor-values[i]) > factor/2*(1+1e-9) {
+				t.Fatalf(
+					"iteration %d: value %d: expected %v within %v but got %v",
+					iteration,
+					i,
+					values[i],
+					factor/2,
+					v*factor,
+				)
+			}
+		}
+
+		if len(values) > 0 && maxQuantized != math.MaxInt16 {
+			t.Errorf(
+				"iteration %d: expected the largest magnitude to be quantized to %d but got %v",
+				iteration,
+				math.MaxInt16,
+				maxQuantized,
+			)
+		}
+	}
+}
+
+// TestEncodeFormats tests that every format round-trips through Encode and Decode.
+func TestEncodeFormats(t *testing.T) {
+	values := []float64{0, 1.5, -2.25, 1000, -0.001}
+
+	testCases := []struct {
+		format    codec.Format
+		tolerance float64
+	}{
+		{format: codec.Format{Sample: codec.Int16}, tolerance: 1000.0 / math.MaxInt16},
+		{
+			format:    codec.Format{Sample: codec.Int16, Compressed: true},
+			tolerance: 1000.0 / math.MaxInt16,
+		},
+		{format: codec.Format{Sample: codec.Int32}, tolerance: 1000.0 / math.MaxInt32},
+		{
+			format:    codec.Format{Sample: codec.Int32, Compressed: true},
+			tolerance: 1000.0 / math.MaxInt32,
+		},
+		{format: codec.Format{Sample: codec.Float32}, tolerance: 1e-4},
+		{format: codec.Format{Sample: codec.Float32, Compressed: true}, tolerance: 1e-4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			raw, factor, err := codec.Encode(values, tc.format)
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+
+			decoded, err := codec.DecodeString(raw, tc.format)
+			if err != nil {
+				t.Fatalf("expected no error decoding but got: %v", err)
+			}
+			if len(decoded) != len(values) {
+				t.Fatalf("expected length %d but got %d", len(values), len(decoded))
+			}
+
+			for i, v := range decoded {
+				if math.Abs(v*factor-values[i]) > tc.tolerance {
+					t.Errorf("value %d: expected %v but got %v", i, values[i], v*factor)
+				}
+			}
+		})
+	}
+}
+
+// TestEncodeSubnormal tests that values too small to be scaled are encoded as zeros in
+// every integer format.
+func TestEncodeSubnormal(t *testing.T) {
+	values := []float64{0, math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64}
+
+	for _, format := range []codec.Format{{Sample: codec.Int16}, {Sample: codec.Int32}} {
+		t.Run(format.String(), func(t *testing.T) {
+			raw, factor, err := codec.Encode(values, format)
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if factor != 1 {
+				t.Errorf("expected factor 1 but got %v", factor)
+			}
+
+			decoded, err := codec.DecodeString(raw, format)
+			if err != nil {
+				t.Fatalf("expected no error decoding but got: %v", err)
+			}
+			for i, v := range decoded {
+				if v != 0 {
+					t.Errorf("value %d: expected 0 but got %v", i, v)
+				}
+			}
+		})
+	}
+}
+
+// TestEncodeErrors tests the values and formats that cannot be encoded.
+func TestEncodeErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		values []float64
+		format codec.Format
+	}{
+		{
+			name:   "Float32 Overflow",
+			values: []float64{1e39},
+			format: codec.Format{Sample: codec.Float32},
+		},
+		{
+			name:   "NaN Int32",
+			values: []float64{math.NaN()},
+			format: codec.Format{Sample: codec.Int32},
+		},
+		{
+			name:   "Unsupported Sample Type",
+			values: []float64{1},
+			format: codec.Format{Sample: codec.SampleType(42)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := codec.Encode(tc.values, tc.format); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package datafetcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Authenticator adds credentials to the requests made by a Client. Authenticators are
+// set with WithAuthenticator, and must be safe for concurrent use.
+type Authenticator interface {
+	// Authenticate adds credentials to req before it is sent. Any request needed to
+	// obtain them, such as a login, is sent through client, which is configured like
+	// the Client req is made by and waits for its Limiter. The context of req bounds the
+	// whole operation.
+	Authenticate(req *http.Request, client *http.Client) error
+}
+
+// Refresher is implemented by Authenticators whose credentials may expire. When a
+// request is answered with 401 Unauthorized, the Client calls Refresh and, if it
+// succeeds, sends the request again once.
+type Refresher interface {
+	// Refresh renews the credentials after req was rejected, sending any request it
+	// needs through client.
+	Refresh(req *http.Request, client *http.Client) error
+}
+
+// basicAuth authenticates requests with HTTP basic authentication.
+type basicAuth struct {
+	credentials CredentialProvider
+}
+
+// BasicAuth returns an Authenticator sending the credentials supplied by provider with
+// HTTP basic authentication. No Authorization header is sent when both the user and the
+// password are empty.
+func BasicAuth(provider CredentialProvider) Authenticator {
+	return basicAuth{credentials: provider}
+}
+
+// Authenticate implements Authenticator.
+func (a basicAuth) Authenticate(req *http.Request, _ *http.Client) error {
+	credentials, err := a.credentials.Credentials(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+
+	if credentials.User != "" || credentials.Password != "" {
+		req.SetBasicAuth(credentials.User, credentials.Password)
+	}
+	return nil
+}
+
+// headerAuth authenticates requests with a token sent in a header.
+type headerAuth struct {
+	header string
+	prefix string
+	token  TokenProvider
+}
+
+// BearerAuth returns an Authenticator sending the token supplied by provider in an
+// "Authorization: Bearer" header.
+func BearerAuth(provider TokenProvider) Authenticator {
+	return headerAuth{header: "Authorization", prefix: "Bearer ", token: provider}
+}
+
+// HeaderAuth returns an Authenticator sending the token supplied by provider as is in
+// the given header, as expected by gateways authenticating requests with API keys, e.g.
+// "X-API-Key".
+func HeaderAuth(header string, provider TokenProvider) Authenticator {
+	return headerAuth{header: header, token: provider}
+}
+
+// Authenticate implements Authenticator.
+func (a headerAuth) Authenticate(req *http.Request, _ *http.Client) error {
+	token, err := a.token.Token(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("%w: empty token", ErrNoCredentials)
+	}
+
+	req.Header.Set(a.header, a.prefix+token)
+	return nil
+}
+
+// SessionLogin configures an Authenticator created with SessionAuth.
+type SessionLogin struct {
+	// URL is the address of the login endpoint. A relative URL is resolved against the
+	// URL of the authenticated request.
+	URL string
+	// Credentials supplies the user and password to log in with.
+	Credentials CredentialProvider
+	// UserField is the name of the form field holding the user. Empty means "username".
+	UserField string
+	// PasswordField is the name of the form field holding the password. Empty means
+	// "password".
+	PasswordField string
+}
+
+// SessionAuthenticator logs in with a form and sends the session cookies set by the
+// login response with every request. It logs in again when a request is rejected with
+// 401 Unauthorized, so expired sessions are renewed transparently. Logins are
+// serialized, and requests rejected at the same time share a single new login.
+type SessionAuthenticator struct {
+	login SessionLogin
+
+	mu      sync.Mutex
+	cookies []*http.Cookie
+}
+
+// SessionAuthenticator implements Authenticator and Refresher.
+var (
+	_ Authenticator = (*SessionAuthenticator)(nil)
+	_ Refresher     = (*SessionAuthenticator)(nil)
+)
+
+// SessionAuth creates a SessionAuthenticator logging in as login tells. The login is
+// performed when the first request is authenticated.
+func SessionAuth(login SessionLogin) *SessionAuthenticator {
+	if login.UserField == "" {
+		login.UserField = "username"
+	}
+	if login.PasswordField == "" {
+		login.PasswordField = "password"
+	}
+	return &SessionAuthenticator{login: login}
+}
+
+// Authenticate implements Authenticator, logging in if there is no session yet.
+func (a *SessionAuthenticator) Authenticate(req *http.Request, client *http.Client) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cookies == nil {
+		if err := a.logIn(req, client); err != nil {
+			return err
+		}
+	}
+
+	for _, cookie := range a.cookies {
+		req.AddCookie(cookie)
+	}
+	return nil
+}
+
+// Refresh implements Refresher, logging in again unless the session req was sent with
+// has already been renewed by another request, whose session is then reused.
+func (a *SessionAuthenticator) Refresh(req *http.Request, client *http.Client) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cookies != nil && !sentWith(req, a.cookies) {
+		return nil
+	}
+	return a.logIn(req, client)
+}
+
+// sentWith reports whether req carries every cookie of cookies, with the same value.
+func sentWith(req *http.Request, cookies []*http.Cookie) bool {
+	for _, cookie := range cookies {
+		sent, err := req.Cookie(cookie.Name)
+		if err != nil || sent.Value != cookie.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// logIn posts the login form and stores the cookies set by the response. It must be
+// called with a.mu held.
+func (a *SessionAuthenticator) logIn(req *http.Request, client *http.Client) error {
+	a.cookies = nil
+
+	loginURL, err := req.URL.Parse(a.login.URL)
+	if err != nil {
+		return fmt.Errorf("error parsing login URL: %w", err)
+	}
+
+	credentials, err := a.login.Credentials.Credentials(req.Context(), loginURL.Hostname())
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set(a.login.UserField, credentials.User)
+	form.Set(a.login.PasswordField, credentials.Password)
+
+	loginReq, err := http.NewRequestWithContext(
+		req.Context(),
+		http.MethodPost,
+		loginURL.String(),
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating login request: %w", err)
+	}
+	loginReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	loginReq.Header.Set("User-Agent", req.Header.Get("User-Agent"))
+
+	// The cookies may be set by a redirect response, which must not be followed.
+	noRedirectClient := *client
+	noRedirectClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := noRedirectClient.Do(loginReq)
+	if err != nil {
+		return fmt.Errorf("error logging in: %w", err)
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("error logging in: %w", &StatusError{
+			StatusCode: resp.StatusCode,
+			URL:        loginURL.Redacted(),
+			Body:       strings.TrimSpace(string(snippet)),
+		})
+	}
+
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return errors.New("error logging in: the response set no session cookie")
+	}
+
+	a.cookies = cookies
+	return nil
+}
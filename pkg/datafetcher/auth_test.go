@@ -0,0 +1,314 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// getWaveformWith requests a waveform from url through a Client using auth, and
+// returns the error.
+func getWaveformWith(t *testing.T, url string, auth datafetcher.Authenticator) error {
+	t.Helper()
+
+	client, err := datafetcher.NewClient(url, datafetcher.WithAuthenticator(auth))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = datafetcher.NewHttpDataFetcher(client).GetWaveform(
+		datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44"),
+	)
+	return err
+}
+
+// TestHeaderAuthenticators tests the authenticators sending a header with every request.
+func TestHeaderAuthenticators(t *testing.T) {
+	testCases := []struct {
+		name     string
+		auth     datafetcher.Authenticator
+		header   string
+		expected string
+	}{
+		{
+			name:     "Basic",
+			auth:     datafetcher.BasicAuth(datafetcher.StaticCredentials("user", "password")),
+			header:   "Authorization",
+			expected: "Basic dXNlcjpwYXNzd29yZA==",
+		},
+		{
+			name:     "Empty Basic",
+			auth:     datafetcher.BasicAuth(datafetcher.StaticCredentials("", "")),
+			header:   "Authorization",
+			expected: "",
+		},
+		{
+			name:     "Bearer",
+			auth:     datafetcher.BearerAuth(datafetcher.StaticToken("secret")),
+			header:   "Authorization",
+			expected: "Bearer secret",
+		},
+		{
+			name:     "API Key",
+			auth:     datafetcher.HeaderAuth("X-API-Key", datafetcher.StaticToken("secret")),
+			header:   "X-API-Key",
+			expected: "secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			mock_server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = r.Header.Get(tc.header)
+					w.WriteHeader(http.StatusNotFound)
+				}),
+			)
+			defer mock_server.Close()
+
+			if err := getWaveformWith(t, mock_server.URL, tc.auth); !errors.Is(
+				err,
+				datafetcher.ErrNotFound,
+			) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected header %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestAuthenticatorError tests that failing to obtain the credentials fails the request
+// without sending it.
+func TestAuthenticatorError(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+		}),
+	)
+	defer mock_server.Close()
+
+	auth := datafetcher.BearerAuth(datafetcher.EnvToken("T8_CLIENT_TEST_UNSET_TOKEN"))
+	if err := getWaveformWith(t, mock_server.URL, auth); !errors.Is(
+		err,
+		datafetcher.ErrNoCredentials,
+	) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+
+	if count := requests.Load(); count != 0 {
+		t.Errorf("expected no requests, got %d", count)
+	}
+}
+
+// newSessionServer creates a mock server accepting the user "user" with password
+// "password" at /login, and rejecting the session it hands out after expireAfter
+// authenticated requests. The number of logins is counted in logins.
+func newSessionServer(t *testing.T, expireAfter int32, logins *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	var session atomic.Int32
+	var uses atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("user") != "user" || r.PostFormValue("pass") != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		logins.Add(1)
+		uses.Store(0)
+		id := session.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: strconv.Itoa(int(id))})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != strconv.Itoa(int(session.Load())) ||
+			uses.Add(1) > expireAfter {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestSessionAuth tests logging in, reusing the session and renewing it once expired.
+func TestSessionAuth(t *testing.T) {
+	var logins atomic.Int32
+	server := newSessionServer(t, 2, &logins)
+
+	auth := datafetcher.SessionAuth(datafetcher.SessionLogin{
+		URL:           "/login",
+		Credentials:   datafetcher.StaticCredentials("user", "password"),
+		UserField:     "user",
+		PasswordField: "pass",
+	})
+
+	client, err := datafetcher.NewClient(server.URL, datafetcher.WithAuthenticator(auth))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	for i := range 3 {
+		if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrNotFound) {
+			t.Fatalf("request %d: expected ErrNotFound, got %v", i, err)
+		}
+	}
+
+	if count := logins.Load(); count != 2 {
+		t.Errorf("expected 2 logins, got %d", count)
+	}
+}
+
+// TestSessionAuthConcurrentRefresh tests that requests rejected at the same time because
+// their session expired share a single new login.
+func TestSessionAuthConcurrentRefresh(t *testing.T) {
+	var session, logins atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		id := session.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: strconv.Itoa(int(id))})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != strconv.Itoa(int(session.Load())) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mock_server := httptest.NewServer(mux)
+	defer mock_server.Close()
+
+	auth := datafetcher.SessionAuth(datafetcher.SessionLogin{
+		URL:         "/login",
+		Credentials: datafetcher.StaticCredentials("user", "password"),
+	})
+	client, err := datafetcher.NewClient(mock_server.URL, datafetcher.WithAuthenticator(auth))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Expire the session.
+	session.Add(1)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count := logins.Load(); count != 2 {
+		t.Errorf("expected 2 logins, got %d", count)
+	}
+}
+
+// TestSessionAuthLimited tests that logins wait for the limiter of the client, without
+// blocking the request they authenticate.
+func TestSessionAuthLimited(t *testing.T) {
+	var logins atomic.Int32
+	server := newSessionServer(t, 10, &logins)
+
+	auth := datafetcher.SessionAuth(datafetcher.SessionLogin{
+		URL:           "/login",
+		Credentials:   datafetcher.StaticCredentials("user", "password"),
+		UserField:     "user",
+		PasswordField: "pass",
+	})
+	client, err := datafetcher.NewClient(
+		server.URL,
+		datafetcher.WithAuthenticator(auth),
+		datafetcher.WithLimits(datafetcher.Limits{Rate: 10, Burst: 1, MaxInFlight: 1}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	start := time.Now()
+	_, err = datafetcher.NewHttpDataFetcher(client).GetWaveform(
+		datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44"),
+	)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	// The login takes the only token, and the request waits 100 ms for the next one.
+	if elapsed < 90*time.Millisecond {
+		t.Errorf("expected the login to wait for the limiter, took %v", elapsed)
+	}
+}
+
+// TestSessionAuthRejected tests that failed logins are reported.
+func TestSessionAuthRejected(t *testing.T) {
+	var logins atomic.Int32
+	server := newSessionServer(t, 1, &logins)
+
+	auth := datafetcher.SessionAuth(datafetcher.SessionLogin{
+		URL:         server.URL + "/login",
+		Credentials: datafetcher.StaticCredentials("user", "wrong"),
+	})
+
+	err := getWaveformWith(t, server.URL, auth)
+	if !errors.Is(err, datafetcher.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// TestSessionAuthCredentialsHost tests that the credential provider receives the
+// hostname of the device.
+func TestSessionAuthCredentialsHost(t *testing.T) {
+	var logins atomic.Int32
+	server := newSessionServer(t, 1, &logins)
+
+	var gotHost string
+	auth := datafetcher.SessionAuth(datafetcher.SessionLogin{
+		URL: "/login",
+		Credentials: datafetcher.CredentialsFunc(
+			func(_ context.Context, host string) (datafetcher.Credentials, error) {
+				gotHost = host
+				return datafetcher.Credentials{User: "user", Password: "password"}, nil
+			},
+		),
+		UserField:     "user",
+		PasswordField: "pass",
+	})
+
+	if err := getWaveformWith(t, server.URL, auth); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if gotHost != "127.0.0.1" {
+		t.Errorf("expected host %q, got %q", "127.0.0.1", gotHost)
+	}
+}
@@ -0,0 +1,191 @@
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// DefaultBatchWorkers is the number of concurrent downloads used by DownloadRange
+// when BatchOptions.Workers is not set.
+const DefaultBatchWorkers = 4
+
+// RecordSource is a DataFetcher that can also list the records it holds.
+type RecordSource interface {
+	DataFetcher
+	RecordLister
+}
+
+// HttpDataFetcher implements RecordSource.
+var _ RecordSource = HttpDataFetcher{}
+
+// RecordKind identifies the kind of a T8 record.
+type RecordKind int
+
+const (
+	// WaveformRecord is a waveform record.
+	WaveformRecord RecordKind = iota + 1
+	// SpectrumRecord is a spectrum record.
+	SpectrumRecord
+)
+
+// String returns a lower-case name for the kind.
+func (k RecordKind) String() string {
+	switch k {
+	case WaveformRecord:
+		return "waveform"
+	case SpectrumRecord:
+		return "spectrum"
+	default:
+		return fmt.Sprintf("RecordKind(%d)", int(k))
+	}
+}
+
+// BatchOptions configures DownloadRange.
+type BatchOptions struct {
+	// Waveforms requests downloading the waveforms in the range.
+	Waveforms bool
+	// Spectra requests downloading the spectra in the range.
+	Spectra bool
+	// Workers is the maximum number of concurrent downloads. Zero means
+	// DefaultBatchWorkers.
+	Workers int
+}
+
+// BatchResult is the outcome of downloading a single record in a batch. Exactly one
+// of Waveform and Spectrum is set, according to Kind, unless Err is not nil.
+type BatchResult struct {
+	Kind     RecordKind
+	Time     time.Time
+	Waveform waveforms.Waveform
+	Spectrum spectra.Spectrum
+	Err      error
+}
+
+// batchJob is a single record to be downloaded by a DownloadRange worker.
+type batchJob struct {
+	kind RecordKind
+	time time.Time
+}
+
+// DownloadRange downloads every waveform and/or spectrum stored for a processing mode
+// within a time range, using up to opts.Workers concurrent requests.
+//
+// The records are listed before DownloadRange returns; the downloads then proceed in
+// the background and their results are sent, in no particular order, on the returned
+// channel, which is closed once every record has been processed. A record that fails
+// to download is reported through the Err field of its result and does not stop the
+// rest of the batch. The caller must either drain the channel or cancel ctx, which
+// stops pending downloads.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the listing and of the downloads.
+//   - source: The RecordSource to list and fetch the records from.
+//   - urlParams: A PmodeUrlParams struct identifying the machine, point and processing mode.
+//   - timeRange: The range the downloaded records must fall in.
+//   - opts: The kinds of record to download and the number of concurrent workers.
+//
+// Returns:
+//   - <-chan BatchResult: The channel the results are sent on.
+//   - error: An error if opts requests no record kind or the records cannot be listed.
+func DownloadRange(
+	ctx context.Context,
+	source RecordSource,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+	opts BatchOptions,
+) (<-chan BatchResult, error) {
+	if !opts.Waveforms && !opts.Spectra {
+		return nil, errors.New("batch options must request waveforms, spectra or both")
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+
+	var jobs []batchJob
+	if opts.Waveforms {
+		times, err := source.ListWaveforms(ctx, urlParams, timeRange)
+		if err != nil {
+			return nil, fmt.Errorf("error listing waveforms: %w", err)
+		}
+		for _, t := range times {
+			jobs = append(jobs, batchJob{kind: WaveformRecord, time: t})
+		}
+	}
+	if opts.Spectra {
+		times, err := source.ListSpectra(ctx, urlParams, timeRange)
+		if err != nil {
+			return nil, fmt.Errorf("error listing spectra: %w", err)
+		}
+		for _, t := range times {
+			jobs = append(jobs, batchJob{kind: SpectrumRecord, time: t})
+		}
+	}
+
+	jobChannel := make(chan batchJob)
+	results := make(chan BatchResult)
+
+	go func() {
+		defer close(jobChannel)
+		for _, job := range jobs {
+			select {
+			case jobChannel <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChannel {
+				if ctx.Err() != nil {
+					return
+				}
+				result := downloadRecord(ctx, source, urlParams, job)
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// downloadRecord fetches the record described by job.
+func downloadRecord(
+	ctx context.Context,
+	source DataFetcher,
+	urlParams PmodeUrlParams,
+	job batchJob,
+) BatchResult {
+	result := BatchResult{Kind: job.kind, Time: job.time}
+	timeParams := PmodeUrlTimeParams{PmodeUrlParams: urlParams, Time: job.time}
+
+	switch job.kind {
+	case WaveformRecord:
+		result.Waveform, result.Err = source.GetWaveformContext(ctx, timeParams)
+	case SpectrumRecord:
+		result.Spectrum, result.Err = source.GetSpectrumContext(ctx, timeParams)
+	}
+
+	return result
+}
@@ -0,0 +1,276 @@
+package datafetcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newBatchServer creates a mock server listing records at the given timestamps and
+// serving them, except for the waveform at failingTimestamp, which fails. The highest
+// number of record requests served concurrently is recorded in maxInFlight.
+func newBatchServer(
+	t *testing.T,
+	timestamps []int64,
+	failingTimestamp int64,
+	maxInFlight *atomic.Int32,
+) *httptest.Server {
+	t.Helper()
+
+	var inFlight atomic.Int32
+
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			if strings.HasSuffix(r.URL.Path, "/") {
+				var links []string
+				for _, timestamp := range timestamps {
+					links = append(
+						links,
+						fmt.Sprintf(`{"_links": {"self": "%s%d"}}`, r.URL.Path, timestamp),
+					)
+				}
+				fmt.Fprintf(w, `{"_items": [%s]}`, strings.Join(links, ","))
+				return
+			}
+
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				previous := maxInFlight.Load()
+				if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			if r.URL.Path == fmt.Sprintf(
+				"/waves/test_machine/test_point/test_pmode/%d",
+				failingTimestamp,
+			) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			var response any = datafetcher.WaveformResponse{
+				RawWaveform: "eJxjZPj//389QwMAEP4D/g==",
+				Factor:      1,
+				SampleRate:  2560,
+			}
+			if strings.HasPrefix(r.URL.Path, "/spectra/") {
+				response = datafetcher.SpectrumResponse{
+					RawSpectrum: "eJxjZPj//389QwMAEP4D/g==",
+					Factor:      1,
+					Fmin:        0,
+					Fmax:        1000,
+				}
+			}
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+		}),
+	)
+}
+
+// TestDownloadRange tests downloading waveforms and spectra concurrently, with a failing record.
+func TestDownloadRange(t *testing.T) {
+	timestamps := []int64{1554907724, 1554907784, 1554907844, 1554907904, 1554907964}
+	failingTimestamp := timestamps[2]
+
+	var maxInFlight atomic.Int32
+	mock_server := newBatchServer(t, timestamps, failingTimestamp, &maxInFlight)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	results, err := datafetcher.DownloadRange(
+		context.Background(),
+		fetcher,
+		params,
+		datafetcher.TimeRange{},
+		datafetcher.BatchOptions{Waveforms: true, Spectra: true, Workers: 2},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	counts := map[datafetcher.RecordKind]int{}
+	failures := 0
+	for result := range results {
+		if result.Err != nil {
+			failures++
+			if result.Kind != datafetcher.WaveformRecord || result.Time.Unix() != failingTimestamp {
+				t.Errorf(
+					"unexpected failure for %s at %v: %v",
+					result.Kind,
+					result.Time,
+					result.Err,
+				)
+			}
+			continue
+		}
+
+		counts[result.Kind]++
+		switch result.Kind {
+		case datafetcher.WaveformRecord:
+			if len(result.Waveform.Samples) != 4 {
+				t.Errorf("expected 4 waveform samples, got %d", len(result.Waveform.Samples))
+			}
+		case datafetcher.SpectrumRecord:
+			if len(result.Spectrum.Magnitudes) != 4 {
+				t.Errorf("expected 4 spectrum magnitudes, got %d", len(result.Spectrum.Magnitudes))
+			}
+		}
+	}
+
+	if failures != 1 {
+		t.Errorf("expected 1 failure, got %d", failures)
+	}
+
+	if counts[datafetcher.WaveformRecord] != len(timestamps)-1 {
+		t.Errorf(
+			"expected %d waveforms, got %d",
+			len(timestamps)-1,
+			counts[datafetcher.WaveformRecord],
+		)
+	}
+
+	if counts[datafetcher.SpectrumRecord] != len(timestamps) {
+		t.Errorf("expected %d spectra, got %d", len(timestamps), counts[datafetcher.SpectrumRecord])
+	}
+
+	if maxInFlight.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent downloads, got %d", maxInFlight.Load())
+	}
+}
+
+// TestDownloadRangeTimeRange tests that only the records within the time range are downloaded.
+func TestDownloadRangeTimeRange(t *testing.T) {
+	timestamps := []int64{1554907724, 1554907784, 1554907844}
+
+	var maxInFlight atomic.Int32
+	mock_server := newBatchServer(t, timestamps, 0, &maxInFlight)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	results, err := datafetcher.DownloadRange(
+		context.Background(),
+		fetcher,
+		params,
+		datafetcher.TimeRange{From: time.Unix(1554907784, 0)},
+		datafetcher.BatchOptions{Spectra: true},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	count := 0
+	for result := range results {
+		if result.Err != nil {
+			t.Errorf("expected no error, got %v", result.Err)
+		}
+		if result.Kind != datafetcher.SpectrumRecord {
+			t.Errorf("expected only spectra, got %s", result.Kind)
+		}
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 results, got %d", count)
+	}
+}
+
+// TestDownloadRangeNothingRequested tests that a batch must request at least one record kind.
+func TestDownloadRangeNothingRequested(t *testing.T) {
+	fetcher := newTestFetcher(t, "http://127.0.0.1:0")
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	results, err := datafetcher.DownloadRange(
+		context.Background(),
+		fetcher,
+		params,
+		datafetcher.TimeRange{},
+		datafetcher.BatchOptions{},
+	)
+	if err == nil {
+		t.Errorf("expected error, got none")
+	}
+
+	if results != nil {
+		t.Errorf("expected nil channel, got %v", results)
+	}
+}
+
+// TestDownloadRangeListingError tests that listing failures are returned before downloading.
+func TestDownloadRangeListingError(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	_, err := datafetcher.DownloadRange(
+		context.Background(),
+		fetcher,
+		params,
+		datafetcher.TimeRange{},
+		datafetcher.BatchOptions{Waveforms: true},
+	)
+	if err == nil {
+		t.Errorf("expected error, got none")
+	}
+}
+
+// TestDownloadRangeCancelled tests that cancelling the context stops the batch.
+func TestDownloadRangeCancelled(t *testing.T) {
+	timestamps := []int64{1554907724, 1554907784, 1554907844, 1554907904, 1554907964}
+
+	var maxInFlight atomic.Int32
+	mock_server := newBatchServer(t, timestamps, 0, &maxInFlight)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := datafetcher.DownloadRange(
+		ctx,
+		fetcher,
+		params,
+		datafetcher.TimeRange{},
+		datafetcher.BatchOptions{Waveforms: true, Workers: 1},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	<-results
+	cancel()
+
+	count := 1
+	for range results {
+		count++
+	}
+
+	if count == len(timestamps) {
+		t.Errorf("expected cancellation to stop the batch early")
+	}
+}
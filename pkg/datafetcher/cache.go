@@ -0,0 +1,385 @@
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// DefaultCacheMaxSize is the maximum size, in bytes, of the records stored by a
+// CachingDataFetcher when WithCacheMaxSize is not used.
+const DefaultCacheMaxSize int64 = 1 << 30
+
+// cacheTempPrefix is the prefix of the files a CachingDataFetcher writes records to
+// before moving them into place.
+const cacheTempPrefix = ".tmp-"
+
+// CachingDataFetcher implements DataFetcher, RecordSource and RawRecordSource.
+var (
+	_ DataFetcher     = (*CachingDataFetcher)(nil)
+	_ RecordSource    = (*CachingDataFetcher)(nil)
+	_ RawRecordSource = (*CachingDataFetcher)(nil)
+)
+
+// CacheOption configures a CachingDataFetcher created with NewCachingDataFetcher.
+type CacheOption func(*cacheConfig)
+
+// cacheConfig collects the settings applied by CacheOption values.
+type cacheConfig struct {
+	maxSize int64
+}
+
+// WithCacheMaxSize sets the maximum size, in bytes, of the records stored in the cache
+// directory. When it is exceeded, the least recently used records are evicted.
+func WithCacheMaxSize(maxSize int64) CacheOption {
+	return func(config *cacheConfig) {
+		config.maxSize = maxSize
+	}
+}
+
+// CachingDataFetcher decorates a RawRecordSource, storing the raw responses of the
+// waveforms and spectra it fetches in a directory, so that later requests for the same
+// records are served from disk without reaching the device. Records stored in a T8 are
+// never modified, so cached records never become stale. Listings are not cached, as new
+// records are stored all the time, and are always forwarded to the source.
+//
+// Records are keyed by host, machine, point, processing mode and timestamp, and stored
+// in a subdirectory of the cache directory for each host. The total size of the records
+// of the host is capped, evicting the least recently used ones.
+// A CachingDataFetcher is safe for concurrent use.
+type CachingDataFetcher struct {
+	source  RawRecordSource
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	size    int64
+}
+
+// cacheEntry describes a record file stored in the cache directory.
+type cacheEntry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// NewCachingDataFetcher creates a CachingDataFetcher storing the records fetched from
+// source below dir, which is created if needed. Records already in dir, e.g. from
+// previous runs, are served as well.
+//
+// Parameters:
+//   - source: The RawRecordSource to fetch the records missing from the cache from.
+//   - dir: The cache directory. It may be shared by fetchers for different hosts, each
+//     of which only indexes, counts against its maximum size and evicts the records of
+//     its own host. It must not be shared by fetchers for the same host.
+//   - opts: Optional settings, such as WithCacheMaxSize.
+//
+// Returns:
+//   - *CachingDataFetcher: The new fetcher.
+//   - error: An error if the cache directory cannot be created or read.
+func NewCachingDataFetcher(
+	source RawRecordSource,
+	dir string,
+	opts ...CacheOption,
+) (*CachingDataFetcher, error) {
+	config := cacheConfig{maxSize: DefaultCacheMaxSize}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.maxSize <= 0 {
+		return nil, fmt.Errorf("invalid cache size %d", config.maxSize)
+	}
+
+	c := &CachingDataFetcher{
+		source:  source,
+		dir:     hostDir(dir, source.Host()),
+		maxSize: config.maxSize,
+		entries: map[string]cacheEntry{},
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %w", err)
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// load indexes the records of the host stored in the cache directory, and removes the
+// temporary files left behind by interrupted downloads.
+func (c *CachingDataFetcher) load() error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), cacheTempPrefix) {
+			_ = os.Remove(path)
+			return nil
+		}
+		if filepath.Ext(path) != recordFileExt {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		c.entries[path] = cacheEntry{size: info.Size(), lastUsed: info.ModTime()}
+		c.size += info.Size()
+
+		return nil
+	})
+}
+
+// Host returns the host of the source.
+func (c *CachingDataFetcher) Host() string {
+	return c.source.Host()
+}
+
+// GetWaveform retrieves a waveform from the cache, or from the source if it is not
+// cached yet. See HttpDataFetcher.GetWaveform.
+func (c *CachingDataFetcher) GetWaveform(urlParams PmodeUrlTimeParams) (waveforms.Waveform, error) {
+	return c.GetWaveformContext(context.Background(), urlParams)
+}
+
+// GetWaveformContext behaves like GetWaveform, but binds the request to the source, if
+// any, to ctx.
+func (c *CachingDataFetcher) GetWaveformContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (waveforms.Waveform, error) {
+	return fetchWaveform(ctx, c, urlParams, nil)
+}
+
+// GetSpectrum retrieves a spectrum from the cache, or from the source if it is not
+// cached yet. See HttpDataFetcher.GetSpectrum.
+func (c *CachingDataFetcher) GetSpectrum(urlParams PmodeUrlTimeParams) (spectra.Spectrum, error) {
+	return c.GetSpectrumContext(context.Background(), urlParams)
+}
+
+// GetSpectrumContext behaves like GetSpectrum, but binds the request to the source, if
+// any, to ctx.
+func (c *CachingDataFetcher) GetSpectrumContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (spectra.Spectrum, error) {
+	return fetchSpectrum(ctx, c, urlParams, nil)
+}
+
+// ListWaveforms lists the waveforms held by the source. Listings are never cached.
+func (c *CachingDataFetcher) ListWaveforms(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return c.source.ListWaveforms(ctx, urlParams, timeRange)
+}
+
+// ListSpectra lists the spectra held by the source. Listings are never cached.
+func (c *CachingDataFetcher) ListSpectra(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return c.source.ListSpectra(ctx, urlParams, timeRange)
+}
+
+// GetRawRecord hands the raw response of a record to consume, reading it from the cache
+// if it is stored there. Otherwise, the record is fetched from the source and stored in
+// the cache as consume reads it. A cached record that consume fails to read, e.g.
+// because the file is corrupt, is evicted and fetched again.
+//
+// Failing to write to the cache directory does not make GetRawRecord fail: the record
+// is then just not cached.
+func (c *CachingDataFetcher) GetRawRecord(
+	ctx context.Context,
+	kind RecordKind,
+	urlParams PmodeUrlTimeParams,
+	consume func(io.Reader) error,
+) error {
+	acquired, err := recordTime(urlParams)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := kindDirs[kind]; !ok {
+		return fmt.Errorf("unsupported record kind %s", kind)
+	}
+
+	path := recordFile(c.dir, kind, urlParams.PmodeUrlParams, acquired)
+
+	if c.readCached(path, consume) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return c.source.GetRawRecord(ctx, kind, urlParams, consume)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), cacheTempPrefix+"*")
+	if err != nil {
+		return c.source.GetRawRecord(ctx, kind, urlParams, consume)
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	writer := &cacheWriter{file: file}
+	err = c.source.GetRawRecord(ctx, kind, urlParams, func(r io.Reader) error {
+		writer.reset()
+
+		tee := io.TeeReader(r, writer)
+		if err := consume(tee); err != nil {
+			return err
+		}
+
+		// Store the whole response even if consume did not read it all.
+		_, err := io.Copy(io.Discard, tee)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if writer.err == nil && writer.size <= c.maxSize && file.Close() == nil &&
+		os.Rename(file.Name(), path) == nil {
+		c.add(path, writer.size)
+	}
+
+	return nil
+}
+
+// readCached hands the record stored at path to consume, and reports whether it
+// succeeded. It returns false if the record is not cached, and evicts it if consume
+// fails.
+func (c *CachingDataFetcher) readCached(path string, consume func(io.Reader) error) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if err := consume(file); err != nil {
+		c.remove(path)
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	c.add(path, info.Size())
+
+	return true
+}
+
+// add records that the file at path, of the given size, was just stored or used, and
+// evicts the least recently used records if the cache grew too large.
+func (c *CachingDataFetcher) add(path string, size int64) {
+	now := time.Now()
+	// The modification time persists the recency of use across runs.
+	_ = os.Chtimes(path, now, now)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[path]; ok {
+		c.size -= entry.size
+	}
+	c.entries[path] = cacheEntry{size: size, lastUsed: now}
+	c.size += size
+
+	c.evict()
+}
+
+// remove deletes the file at path from the cache.
+func (c *CachingDataFetcher) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[path]; ok {
+		c.size -= entry.size
+		delete(c.entries, path)
+	}
+	_ = os.Remove(path)
+}
+
+// evict deletes the least recently used records until the cache fits its maximum size.
+// It must be called with c.mu held.
+func (c *CachingDataFetcher) evict() {
+	if c.size <= c.maxSize {
+		return
+	}
+
+	paths := make([]string, 0, len(c.entries))
+	for path := range c.entries {
+		paths = append(paths, path)
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		return c.entries[a].lastUsed.Compare(c.entries[b].lastUsed)
+	})
+
+	for _, path := range paths {
+		if c.size <= c.maxSize {
+			break
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		c.size -= c.entries[path].size
+		delete(c.entries, path)
+	}
+}
+
+// cacheWriter writes a response to a cache file on a best-effort basis: write errors
+// are recorded instead of being returned, so that they do not interrupt the reading of
+// the response.
+type cacheWriter struct {
+	file *os.File
+	size int64
+	err  error
+}
+
+// Write implements io.Writer.
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		n, err := w.file.Write(p)
+		w.size += int64(n)
+		w.err = err
+	}
+	return len(p), nil
+}
+
+// reset discards what was written so far, so that a retried response can be written.
+func (w *cacheWriter) reset() {
+	w.size = 0
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		w.err = err
+		return
+	}
+	w.err = w.file.Truncate(0)
+}
@@ -0,0 +1,366 @@
+package datafetcher_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// cacheTestResponse is the waveform served by the mock servers of the cache tests.
+var cacheTestResponse = datafetcher.WaveformResponse{
+	RawWaveform: "eJxjZPj//389QwMAEP4D/g==",
+	Factor:      2.0,
+	SampleRate:  2560,
+}
+
+// requestCounter counts the requests received for each path.
+type requestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// add counts a request for path.
+func (c *requestCounter) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	c.counts[path]++
+}
+
+// get returns the number of requests received for path.
+func (c *requestCounter) get(path string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[path]
+}
+
+// newCacheServer creates a mock server serving cacheTestResponse for every waveform
+// and spectrum, except for timestamp 0, which is not found, and counts the requests.
+func newCacheServer(t *testing.T, counter *requestCounter) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counter.add(r.URL.Path)
+
+			if strings.HasSuffix(r.URL.Path, "/0") {
+				http.NotFound(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(cacheTestResponse); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+		}),
+	)
+}
+
+// newTestCache creates a CachingDataFetcher in front of the given server.
+func newTestCache(
+	t *testing.T,
+	url, dir string,
+	opts ...datafetcher.CacheOption,
+) *datafetcher.CachingDataFetcher {
+	t.Helper()
+
+	cache, err := datafetcher.NewCachingDataFetcher(newTestFetcher(t, url), dir, opts...)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	return cache
+}
+
+// cachedFiles returns the record files stored below dir.
+func cachedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to walk cache directory: %v", err)
+	}
+
+	return files
+}
+
+// TestCachingDataFetcherServesRepeatRequests tests that cached records are served
+// without reaching the server, also by a new fetcher once the server is gone.
+func TestCachingDataFetcherServesRepeatRequests(t *testing.T) {
+	var counter requestCounter
+	mock_server := newCacheServer(t, &counter)
+	defer mock_server.Close()
+
+	dir := t.TempDir()
+	cache := newTestCache(t, mock_server.URL, dir)
+
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+	wavePath := "/waves/test_machine/test_point/test_pmode/1554907724"
+	spectrumPath := "/spectra/test_machine/test_point/test_pmode/1554907724"
+
+	first, err := cache.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	second, err := cache.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected cached waveform %+v, got %+v", first, second)
+	}
+
+	if _, err := cache.GetSpectrum(params); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if count := counter.get(wavePath); count != 1 {
+		t.Errorf("expected 1 waveform request, got %d", count)
+	}
+	if count := counter.get(spectrumPath); count != 1 {
+		t.Errorf("expected 1 spectrum request, got %d", count)
+	}
+
+	url := mock_server.URL
+	mock_server.Close()
+
+	offline := newTestCache(t, url, dir)
+
+	third, err := offline.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(first, third) {
+		t.Errorf("expected cached waveform %+v, got %+v", first, third)
+	}
+
+	if _, err := offline.GetSpectrum(params); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestCachingDataFetcherEvictsLeastRecentlyUsed tests that the cache size is capped by
+// evicting the least recently used records.
+func TestCachingDataFetcherEvictsLeastRecentlyUsed(t *testing.T) {
+	var counter requestCounter
+	mock_server := newCacheServer(t, &counter)
+	defer mock_server.Close()
+
+	encoded, err := json.Marshal(cacheTestResponse)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	// json.Encoder terminates each value with a newline.
+	recordSize := int64(len(encoded) + 1)
+
+	dir := t.TempDir()
+	cache := newTestCache(t, mock_server.URL, dir, datafetcher.WithCacheMaxSize(2*recordSize))
+
+	fetch := func(dateTime string) {
+		t.Helper()
+		params := datafetcher.NewPmodeUrlTimeParams(
+			"test_machine",
+			"test_point",
+			"test_pmode",
+			dateTime,
+		)
+		if _, err := cache.GetWaveform(params); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	fetch("2019-04-10T14:48:44")
+	fetch("2019-04-10T14:49:44")
+	fetch("2019-04-10T14:48:44")
+	fetch("2019-04-10T14:50:44")
+
+	if files := cachedFiles(t, dir); len(files) != 2 {
+		t.Errorf("expected 2 cached records, got %v", files)
+	}
+
+	fetch("2019-04-10T14:48:44")
+	fetch("2019-04-10T14:49:44")
+
+	expectedCounts := map[string]int{
+		"/waves/test_machine/test_point/test_pmode/1554907724": 1,
+		"/waves/test_machine/test_point/test_pmode/1554907784": 2,
+		"/waves/test_machine/test_point/test_pmode/1554907844": 1,
+	}
+	for path, expected := range expectedCounts {
+		if count := counter.get(path); count != expected {
+			t.Errorf("expected %d requests for %s, got %d", expected, path, count)
+		}
+	}
+}
+
+// TestCachingDataFetcherSharedDirectory tests that fetchers for different hosts sharing a
+// cache directory only count and evict their own records.
+func TestCachingDataFetcherSharedDirectory(t *testing.T) {
+	var counters [2]requestCounter
+	servers := [2]*httptest.Server{
+		newCacheServer(t, &counters[0]),
+		newCacheServer(t, &counters[1]),
+	}
+	for _, server := range servers {
+		defer server.Close()
+	}
+
+	encoded, err := json.Marshal(cacheTestResponse)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	// json.Encoder terminates each value with a newline.
+	maxSize := datafetcher.WithCacheMaxSize(2 * int64(len(encoded)+1))
+
+	dir := t.TempDir()
+	dateTimes := []string{"2019-04-10T14:48:44", "2019-04-10T14:49:44"}
+	fetchAll := func(cache *datafetcher.CachingDataFetcher) {
+		t.Helper()
+		for _, dateTime := range dateTimes {
+			params := datafetcher.NewPmodeUrlTimeParams(
+				"test_machine",
+				"test_point",
+				"test_pmode",
+				dateTime,
+			)
+			if _, err := cache.GetWaveform(params); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+	}
+
+	first := newTestCache(t, servers[0].URL, dir, maxSize)
+	fetchAll(first)
+	second := newTestCache(t, servers[1].URL, dir, maxSize)
+	fetchAll(second)
+
+	if files := cachedFiles(t, dir); len(files) != 4 {
+		t.Errorf("expected 4 cached records, got %v", files)
+	}
+
+	fetchAll(first)
+	fetchAll(newTestCache(t, servers[0].URL, dir, maxSize))
+
+	for i := range servers {
+		for _, path := range []string{
+			"/waves/test_machine/test_point/test_pmode/1554907724",
+			"/waves/test_machine/test_point/test_pmode/1554907784",
+		} {
+			if count := counters[i].get(path); count != 1 {
+				t.Errorf("expected 1 request to server %d for %s, got %d", i, path, count)
+			}
+		}
+	}
+}
+
+// TestCachingDataFetcherCorruptRecord tests that a corrupt cached record is fetched again.
+func TestCachingDataFetcherCorruptRecord(t *testing.T) {
+	var counter requestCounter
+	mock_server := newCacheServer(t, &counter)
+	defer mock_server.Close()
+
+	dir := t.TempDir()
+	cache := newTestCache(t, mock_server.URL, dir)
+
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	expected, err := cache.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files := cachedFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 cached record, got %v", files)
+	}
+	if err := os.WriteFile(files[0], []byte(`{"data": "trunc`), 0o644); err != nil {
+		t.Fatalf("failed to corrupt cached record: %v", err)
+	}
+
+	waveform, err := cache.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(waveform, expected) {
+		t.Errorf("expected waveform %+v, got %+v", expected, waveform)
+	}
+
+	if count := counter.get("/waves/test_machine/test_point/test_pmode/1554907724"); count != 2 {
+		t.Errorf("expected 2 requests, got %d", count)
+	}
+}
+
+// TestCachingDataFetcherErrorsNotCached tests that failed requests are not cached.
+func TestCachingDataFetcherErrorsNotCached(t *testing.T) {
+	var counter requestCounter
+	mock_server := newCacheServer(t, &counter)
+	defer mock_server.Close()
+
+	dir := t.TempDir()
+	cache := newTestCache(t, mock_server.URL, dir)
+
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"1970-01-01T00:00:00",
+	)
+
+	for range 2 {
+		if _, err := cache.GetWaveform(params); !errors.Is(err, datafetcher.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+
+	if count := counter.get("/waves/test_machine/test_point/test_pmode/0"); count != 2 {
+		t.Errorf("expected 2 requests, got %d", count)
+	}
+
+	if files := cachedFiles(t, dir); len(files) != 0 {
+		t.Errorf("expected no cached records, got %v", files)
+	}
+}
+
+// TestNewCachingDataFetcherInvalidSize tests that the cache size must be positive.
+func TestNewCachingDataFetcherInvalidSize(t *testing.T) {
+	_, err := datafetcher.NewCachingDataFetcher(
+		newTestFetcher(t, "http://127.0.0.1:0"),
+		t.TempDir(),
+		datafetcher.WithCacheMaxSize(0),
+	)
+	if err == nil {
+		t.Errorf("expected error, got none")
+	}
+}
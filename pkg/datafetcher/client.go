@@ -0,0 +1,454 @@
+package datafetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is the User-Agent header sent by a Client unless overridden with
+// WithUserAgent.
+const DefaultUserAgent = "t8-client-go"
+
+// Client holds the connection settings shared by every request made to a single T8
+// device: its base URL, its Authenticator and the underlying *http.Client. A Client is
+// meant to be created once and reused, so that connection pools are shared between
+// requests and credentials stay out of the per-request parameters.
+//
+// A Client is safe for concurrent use by multiple goroutines.
+type Client struct {
+	host        string
+	auth        Authenticator
+	userAgent   string
+	httpClient  *http.Client
+	authClient  *http.Client
+	retryPolicy RetryPolicy
+	logger      *slog.Logger
+	metrics     Metrics
+	limiter     *Limiter
+}
+
+// clientConfig collects the values set by ClientOption functions before NewClient
+// resolves them into a Client.
+type clientConfig struct {
+	auth        Authenticator
+	userAgent   string
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	tlsConfig   *tls.Config
+	tls         tlsOptions
+	timeout     time.Duration
+	retryPolicy RetryPolicy
+	logger      *slog.Logger
+	metrics     Metrics
+	limiter     *Limiter
+	limits      *Limits
+}
+
+// ClientOption configures a Client created with NewClient.
+type ClientOption func(*clientConfig)
+
+// WithAuthenticator sets the Authenticator adding credentials to every request. A
+// Client without one sends no credentials.
+func WithAuthenticator(auth Authenticator) ClientOption {
+	return func(c *clientConfig) {
+		c.auth = auth
+	}
+}
+
+// WithCredentials sets the user and password sent with every request using HTTP
+// basic authentication. It is a shorthand for WithAuthenticator with BasicAuth and
+// StaticCredentials.
+func WithCredentials(user, password string) ClientOption {
+	return WithAuthenticator(BasicAuth(StaticCredentials(user, password)))
+}
+
+// WithHTTPClient makes the Client send its requests through httpClient instead of a
+// freshly created one. The given client is not modified: options such as WithTimeout,
+// WithTransport or WithTLSConfig are applied to a copy of it.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *clientConfig) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the http.RoundTripper used to perform requests.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *clientConfig) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when connecting to the device. Other TLS
+// options, such as WithCAFile, are applied to a copy of it. TLS options can only be
+// combined with WithTransport when the transport is an *http.Transport.
+func WithTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(c *clientConfig) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithTimeout sets the overall time limit for each request, including connection,
+// redirects and reading the response body. A zero value means no timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient creates a Client for the T8 REST API located at host.
+//
+// Parameters:
+//   - host: The base URL of the T8 REST API, e.g. "https://t8.example.com/rest".
+//   - opts: Optional settings such as authentication, transport, user agent, TLS
+//     configuration, timeout, retry policy, rate limits, logging and metrics.
+//
+// Returns:
+//   - *Client: The configured client.
+//   - error: An error if host is not a valid absolute HTTP(S) URL, the options are
+//     inconsistent, or the certificates or keys they name cannot be loaded.
+func NewClient(host string, opts ...ClientOption) (*Client, error) {
+	parsedHost, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing host: %w", err)
+	}
+	if parsedHost.Scheme != "http" && parsedHost.Scheme != "https" {
+		return nil, fmt.Errorf("host must be an http or https URL, got %q", host)
+	}
+	if parsedHost.Host == "" {
+		return nil, fmt.Errorf("host must include a hostname, got %q", host)
+	}
+
+	config := clientConfig{userAgent: DefaultUserAgent}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	httpClient := &http.Client{}
+	if config.httpClient != nil {
+		clientCopy := *config.httpClient
+		httpClient = &clientCopy
+	}
+	if config.transport != nil {
+		httpClient.Transport = config.transport
+	}
+	if config.timeout != 0 {
+		httpClient.Timeout = config.timeout
+	}
+	tlsConfig := config.tlsConfig
+	if config.tls.isSet() {
+		base := tlsConfig
+		if transport, ok := httpClient.Transport.(*http.Transport); ok && base == nil {
+			base = transport.TLSClientConfig
+		}
+		logger := config.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		tlsConfig, err = buildTLSConfig(base, config.tls, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tlsConfig != nil {
+		transport, err := transportWithTLS(httpClient.Transport, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = transport
+	}
+
+	limiter := config.limiter
+	if config.limits != nil {
+		if limiter != nil {
+			return nil, errors.New("WithLimits and WithLimiter are mutually exclusive")
+		}
+		limiter, err = NewLimiter(*config.limits)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The requests sent by the Authenticator wait for the limiter too.
+	authClient := httpClient
+	if limiter != nil {
+		clientCopy := *httpClient
+		clientCopy.Transport = &limitedTransport{base: httpClient.Transport, limiter: limiter}
+		authClient = &clientCopy
+	}
+
+	logger := config.logger
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	return &Client{
+		host:        strings.TrimRight(host, "/"),
+		auth:        config.auth,
+		userAgent:   config.userAgent,
+		httpClient:  httpClient,
+		authClient:  authClient,
+		retryPolicy: config.retryPolicy,
+		logger:      logger,
+		metrics:     config.metrics,
+		limiter:     limiter,
+	}, nil
+}
+
+// transportWithTLS returns a copy of transport using tlsConfig. A nil transport is
+// replaced by a copy of http.DefaultTransport.
+func transportWithTLS(
+	transport http.RoundTripper,
+	tlsConfig *tls.Config,
+) (http.RoundTripper, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("TLS configuration requires an *http.Transport")
+	}
+
+	httpTransport = httpTransport.Clone()
+	httpTransport.TLSClientConfig = tlsConfig
+
+	return httpTransport, nil
+}
+
+// Host returns the base URL of the T8 REST API the client talks to, without a
+// trailing slash.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// get performs an authenticated GET request for the given path, relative to the
+// client's host, and returns the response body. Any status code other than 200 OK
+// is reported as a *StatusError. Transient failures are retried according to the client's
+// retry policy.
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	var body []byte
+	err := c.getStream(ctx, path, func(r io.Reader) error {
+		var err error
+		body, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// getStream performs an authenticated GET request for the given path, like get, but
+// hands the response body to consume as it is received instead of buffering it. The
+// body is closed once consume returns. Failures reading the body are retried like
+// failed requests, calling consume again with the body of the new response, so consume
+// must not keep any state across calls; any other error returned by consume is returned
+// as is.
+//
+// A request rejected with 401 Unauthorized is sent again once if the client's
+// Authenticator is a Refresher and refreshing the credentials succeeds.
+func (c *Client) getStream(ctx context.Context, path string, consume func(io.Reader) error) error {
+	requestURL := c.host + path
+	endpoint := requestEndpoint(path)
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.getOnce(ctx, requestURL, endpoint, attempt, consume)
+		if err == nil {
+			return nil
+		}
+
+		// Expired credentials are renewed and the request sent again once, without
+		// counting it as a retry.
+		if refresher, ok := c.auth.(Refresher); ok && !refreshed && resp != nil &&
+			resp.StatusCode == http.StatusUnauthorized {
+			refreshed = true
+			if err := refresher.Refresh(resp.Request, c.authClient); err != nil {
+				return fmt.Errorf("error refreshing credentials: %w", err)
+			}
+			attempt--
+			continue
+		}
+
+		if attempt >= c.retryPolicy.MaxAttempts || ctx.Err() != nil ||
+			!c.retryPolicy.shouldRetry(resp, err) {
+			return err
+		}
+
+		retryAttempt := RetryAttempt{
+			Attempt: attempt,
+			URL:     requestURL,
+			Err:     err,
+			Delay:   c.retryPolicy.backoff(attempt),
+		}
+		if resp != nil {
+			retryAttempt.StatusCode = resp.StatusCode
+			if delay, ok := parseRetryAfter(
+				resp.Header.Get("Retry-After"),
+				time.Now(),
+				c.retryPolicy.maxRetryAfter(),
+			); ok {
+				retryAttempt.Delay = delay
+			}
+		}
+
+		c.logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"retrying T8 request",
+			slog.String("url", redactURL(requestURL)),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", retryAttempt.Delay),
+			slog.Any("error", err),
+		)
+		if c.retryPolicy.OnRetry != nil {
+			c.retryPolicy.OnRetry(retryAttempt)
+		}
+
+		if err := sleepContext(ctx, retryAttempt.Delay); err != nil {
+			return fmt.Errorf("error waiting to retry request: %w", err)
+		}
+	}
+}
+
+// getOnce performs a single attempt of an authenticated GET request for requestURL and
+// hands the response body to consume. When the server answers with a status code other
+// than 200 OK, the (already closed) response is returned along with the error, so that
+// the caller can inspect its status code and headers. When reading the body fails, the
+// read error is returned instead of the one returned by consume, so that the caller can
+// tell whether the attempt is worth retrying. The attempt is logged and measured as
+// the number attempt of a request to endpoint, from the time the client's limiter lets
+// it through.
+func (c *Client) getOnce(
+	ctx context.Context,
+	requestURL, endpoint string,
+	attempt int,
+	consume func(io.Reader) error,
+) (resp *http.Response, err error) {
+	start := time.Now()
+	body := &bodyReader{}
+	// received is the response, also when it is not returned.
+	var received *http.Response
+
+	// Tracing is only worth it when successful requests are logged or measured too.
+	var tracer *requestTracer
+	if c.observed() {
+		tracer = &requestTracer{}
+		ctx = httptrace.WithClientTrace(ctx, tracer.clientTrace())
+	}
+	defer func() {
+		if tracer == nil && err == nil {
+			return
+		}
+
+		stats := RequestStats{
+			Host:     c.host,
+			Endpoint: endpoint,
+			URL:      redactURL(requestURL),
+			Attempt:  attempt,
+			Duration: time.Since(start),
+			Bytes:    body.n,
+			Err:      err,
+		}
+		if received != nil {
+			stats.StatusCode = received.StatusCode
+		}
+		if tracer != nil {
+			stats.Trace = tracer.result()
+		}
+		c.observeRequest(ctx, stats)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req, c.authClient); err != nil {
+			return nil, fmt.Errorf("error authenticating request: %w", err)
+		}
+	}
+
+	// The limiter is waited for once the request is authenticated, as any login sent by
+	// the Authenticator waits for it too.
+	if c.limiter != nil {
+		release, err := c.limiter.acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error waiting for rate limiter: %w", err)
+		}
+		defer release()
+		start = time.Now()
+	}
+
+	received, err = c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making GET request: %w", &transportError{err})
+	}
+	resp = received
+	defer func() {
+		if err := received.Body.Close(); err != nil {
+			c.logger.WarnContext(ctx, "error closing response body", slog.Any("error", err))
+		}
+	}()
+
+	body.r = resp.Body
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+		// Drain the body so that the connection can be reused by a retry.
+		_, _ = io.Copy(io.Discard, body)
+		return resp, &StatusError{
+			StatusCode: resp.StatusCode,
+			URL:        redactURL(requestURL),
+			Body:       strings.TrimSpace(string(snippet)),
+		}
+	}
+
+	if err := consume(body); err != nil {
+		if body.err != nil {
+			return nil, fmt.Errorf(
+				"error reading response body: %w",
+				&transportError{body.err},
+			)
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// bodyReader wraps a response body, counting the bytes read from it and recording the
+// first error reading from it, other than io.EOF.
+type bodyReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+// Read implements io.Reader.
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
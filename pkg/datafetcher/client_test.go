@@ -0,0 +1,243 @@
+package datafetcher_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestNewClientInvalidHost tests that hosts which are not absolute HTTP(S) URLs are rejected.
+func TestNewClientInvalidHost(t *testing.T) {
+	testCases := []struct {
+		name string
+		host string
+	}{
+		{name: "Empty Host", host: ""},
+		{name: "Missing Scheme", host: "t8.example.com/rest"},
+		{name: "Unsupported Scheme", host: "ftp://t8.example.com/rest"},
+		{name: "Missing Hostname", host: "https:///rest"},
+		{name: "Malformed URL", host: "https://t8.example.com/%zz"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := datafetcher.NewClient(tc.host)
+			if err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if client != nil {
+				t.Errorf("expected nil client, got %v", client)
+			}
+		})
+	}
+}
+
+// TestNewClientTrimsHost tests that trailing slashes are removed from the host.
+func TestNewClientTrimsHost(t *testing.T) {
+	client, err := datafetcher.NewClient("https://t8.example.com/rest/")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if client.Host() != "https://t8.example.com/rest" {
+		t.Errorf("expected host without trailing slash, got %q", client.Host())
+	}
+}
+
+// TestClientRequestHeaders tests that credentials and user agent are sent with each request.
+func TestClientRequestHeaders(t *testing.T) {
+	var gotUser, gotPassword, gotUserAgent, gotPath string
+	var gotAuth bool
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotUser, gotPassword, gotAuth = r.BasicAuth()
+			gotUserAgent = r.UserAgent()
+			gotPath = r.URL.EscapedPath()
+			w.WriteHeader(http.StatusInternalServerError)
+		}),
+	)
+	defer mock_server.Close()
+
+	client, err := datafetcher.NewClient(
+		mock_server.URL+"/rest/",
+		datafetcher.WithCredentials("user", "password"),
+		datafetcher.WithUserAgent("test-agent"),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+	if _, err := fetcher.GetWaveform(params); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if !gotAuth || gotUser != "user" || gotPassword != "password" {
+		t.Errorf(
+			"expected basic auth user/password, got %q/%q (present: %v)",
+			gotUser,
+			gotPassword,
+			gotAuth,
+		)
+	}
+
+	if gotUserAgent != "test-agent" {
+		t.Errorf("expected user agent %q, got %q", "test-agent", gotUserAgent)
+	}
+
+	expectedPath := "/rest/waves/test%20machine/test_point/test_pmode/1554907724"
+	if gotPath != expectedPath {
+		t.Errorf("expected path %q, got %q", expectedPath, gotPath)
+	}
+}
+
+// TestClientWithTransport tests that requests go through an injected transport.
+func TestClientWithTransport(t *testing.T) {
+	called := false
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		if req.UserAgent() != datafetcher.DefaultUserAgent {
+			t.Errorf("expected default user agent, got %q", req.UserAgent())
+		}
+		return nil, errors.New("transport failure")
+	})
+
+	client, err := datafetcher.NewClient(
+		"https://t8.example.com/rest",
+		datafetcher.WithTransport(transport),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+	if _, err := fetcher.GetSpectrum(params); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if !called {
+		t.Errorf("expected the injected transport to be used")
+	}
+}
+
+// TestClientWithHTTPClientNotModified tests that options do not mutate an injected *http.Client.
+func TestClientWithHTTPClientNotModified(t *testing.T) {
+	httpClient := &http.Client{}
+
+	_, err := datafetcher.NewClient(
+		"https://t8.example.com/rest",
+		datafetcher.WithHTTPClient(httpClient),
+		datafetcher.WithTimeout(time.Second),
+		datafetcher.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if httpClient.Timeout != 0 || httpClient.Transport != nil {
+		t.Errorf("expected injected client to be left untouched, got %+v", httpClient)
+	}
+}
+
+// TestClientTLSConfigRequiresHTTPTransport tests that TLS settings cannot be applied to
+// arbitrary round trippers.
+func TestClientTLSConfigRequiresHTTPTransport(t *testing.T) {
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("unused")
+	})
+
+	_, err := datafetcher.NewClient(
+		"https://t8.example.com/rest",
+		datafetcher.WithTransport(transport),
+		datafetcher.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}),
+	)
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+// TestClientWithTimeout tests that the configured timeout bounds each request.
+func TestClientWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}),
+	)
+	defer mock_server.Close()
+	defer close(release)
+
+	client, err := datafetcher.NewClient(
+		mock_server.URL,
+		datafetcher.WithTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	start := time.Now()
+	_, err = fetcher.GetWaveformContext(context.Background(), params)
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected request to time out quickly, took %v", elapsed)
+	}
+}
+
+// TestHttpDataFetcherWithoutClient tests that a zero HttpDataFetcher reports an error
+// instead of panicking.
+func TestHttpDataFetcherWithoutClient(t *testing.T) {
+	fetcher := datafetcher.HttpDataFetcher{}
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	if _, err := fetcher.GetWaveform(params); err == nil {
+		t.Errorf("expected error from GetWaveform, got none")
+	}
+
+	if _, err := fetcher.GetSpectrum(params); err == nil {
+		t.Errorf("expected error from GetSpectrum, got none")
+	}
+}
@@ -0,0 +1,247 @@
+package datafetcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credentials are the user and password an Authenticator logs in with.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialProvider supplies the credentials for a T8 device. Providers are asked for
+// the credentials every time they are needed, so they can serve secrets that change
+// while the Client is in use.
+type CredentialProvider interface {
+	// Credentials returns the credentials for the device at host, the hostname of the
+	// request being authenticated.
+	Credentials(ctx context.Context, host string) (Credentials, error)
+}
+
+// CredentialsFunc adapts a function to a CredentialProvider, e.g. to read the
+// credentials from an OS keyring or a secret manager.
+type CredentialsFunc func(ctx context.Context, host string) (Credentials, error)
+
+// Credentials implements CredentialProvider.
+func (f CredentialsFunc) Credentials(ctx context.Context, host string) (Credentials, error) {
+	return f(ctx, host)
+}
+
+// TokenProvider supplies the token for a T8 device, like CredentialProvider does with
+// user and password.
+type TokenProvider interface {
+	// Token returns the token for the device at host, the hostname of the request being
+	// authenticated.
+	Token(ctx context.Context, host string) (string, error)
+}
+
+// TokenFunc adapts a function to a TokenProvider.
+type TokenFunc func(ctx context.Context, host string) (string, error)
+
+// Token implements TokenProvider.
+func (f TokenFunc) Token(ctx context.Context, host string) (string, error) {
+	return f(ctx, host)
+}
+
+// StaticCredentials returns a CredentialProvider always supplying user and password.
+func StaticCredentials(user, password string) CredentialProvider {
+	return CredentialsFunc(func(context.Context, string) (Credentials, error) {
+		return Credentials{User: user, Password: password}, nil
+	})
+}
+
+// StaticToken returns a TokenProvider always supplying token.
+func StaticToken(token string) TokenProvider {
+	return TokenFunc(func(context.Context, string) (string, error) {
+		return token, nil
+	})
+}
+
+// EnvCredentials returns a CredentialProvider reading the user and password from the
+// given environment variables. Unset variables yield empty values.
+func EnvCredentials(userVar, passwordVar string) CredentialProvider {
+	return CredentialsFunc(func(context.Context, string) (Credentials, error) {
+		return Credentials{User: os.Getenv(userVar), Password: os.Getenv(passwordVar)}, nil
+	})
+}
+
+// EnvToken returns a TokenProvider reading the token from the given environment
+// variable. An unset variable is reported as ErrNoCredentials.
+func EnvToken(tokenVar string) TokenProvider {
+	return TokenFunc(func(context.Context, string) (string, error) {
+		token, ok := os.LookupEnv(tokenVar)
+		if !ok {
+			return "", fmt.Errorf("%w: %s is not set", ErrNoCredentials, tokenVar)
+		}
+		return token, nil
+	})
+}
+
+// FileCredentials returns a CredentialProvider reading the credentials from the first
+// line of the file at path, written as "user:password". The file is read every time
+// the credentials are needed, so it can be rotated.
+func FileCredentials(path string) CredentialProvider {
+	return CredentialsFunc(func(context.Context, string) (Credentials, error) {
+		line, err := readFirstLine(path)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		user, password, ok := strings.Cut(line, ":")
+		if !ok {
+			return Credentials{}, fmt.Errorf(
+				"%w: %s does not hold a user:password line",
+				ErrNoCredentials,
+				path,
+			)
+		}
+		return Credentials{User: user, Password: password}, nil
+	})
+}
+
+// FileToken returns a TokenProvider reading the token from the first line of the file
+// at path. The file is read every time the token is needed, so it can be rotated.
+func FileToken(path string) TokenProvider {
+	return TokenFunc(func(context.Context, string) (string, error) {
+		return readFirstLine(path)
+	})
+}
+
+// readFirstLine returns the first line of the file at path, without surrounding
+// white space.
+func readFirstLine(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading credentials file: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// NetrcCredentials returns a CredentialProvider looking up the login and password of
+// the requested host in a netrc file, falling back to its default entry. An empty path
+// means the file named by the NETRC environment variable or, if it is unset, .netrc in
+// the home directory of the user. Hosts without an entry are reported as
+// ErrNoCredentials.
+func NetrcCredentials(path string) CredentialProvider {
+	return CredentialsFunc(func(_ context.Context, host string) (Credentials, error) {
+		netrcPath, err := netrcPath(path)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		file, err := os.Open(netrcPath)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("error opening netrc file: %w", err)
+		}
+		defer file.Close()
+
+		credentials, ok, err := lookupNetrc(bufio.NewScanner(file), host)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("error reading netrc file: %w", err)
+		}
+		if !ok {
+			return Credentials{}, fmt.Errorf(
+				"%w: no entry for %s in %s",
+				ErrNoCredentials,
+				host,
+				netrcPath,
+			)
+		}
+		return credentials, nil
+	})
+}
+
+// netrcPath returns the path of the netrc file NetrcCredentials reads.
+func netrcPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if path := os.Getenv("NETRC"); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating netrc file: %w", err)
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// lookupNetrc scans the lines of a netrc file and returns the credentials of the entry
+// for host or, if there is none, of the default entry. Macro definitions are skipped.
+func lookupNetrc(scanner *bufio.Scanner, host string) (Credentials, bool, error) {
+	var (
+		found, fallback       Credentials
+		hasFound, hasFallback bool
+		// current points to the credentials being filled by the entry being read, or
+		// is nil if the entry is irrelevant.
+		current *Credentials
+		inMacro bool
+		pending string
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+	tokens:
+		for _, token := range strings.Fields(line) {
+			if strings.HasPrefix(token, "#") && pending == "" {
+				break
+			}
+
+			switch pending {
+			case "machine":
+				current = nil
+				if token == host && !hasFound {
+					current, hasFound = &found, true
+				}
+			case "login":
+				if current != nil {
+					current.User = token
+				}
+			case "password":
+				if current != nil {
+					current.Password = token
+				}
+			case "account":
+			default:
+				switch token {
+				case "default":
+					current = nil
+					if !hasFallback {
+						current, hasFallback = &fallback, true
+					}
+				case "macdef":
+					// The macro name ends the line, and its body the following ones.
+					current = nil
+					inMacro = true
+					break tokens
+				case "machine", "login", "password", "account":
+					pending = token
+				}
+				continue
+			}
+			pending = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credentials{}, false, err
+	}
+
+	if hasFound {
+		return found, true, nil
+	}
+	return fallback, hasFallback, nil
+}
@@ -0,0 +1,143 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// writeFile writes content to a file named name in a temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// TestNetrcCredentials tests looking up hosts in a netrc file.
+func TestNetrcCredentials(t *testing.T) {
+	path := writeFile(t, "netrc", `# T8 devices
+machine t8.example.com login alice password secret1
+macdef init
+machine macro.example.com login mallory password macro
+
+machine other.example.com
+	login bob
+	password "secret2"
+	account ignored
+default login guest password guest
+`)
+
+	testCases := []struct {
+		name     string
+		host     string
+		expected datafetcher.Credentials
+	}{
+		{
+			name:     "Single Line Entry",
+			host:     "t8.example.com",
+			expected: datafetcher.Credentials{User: "alice", Password: "secret1"},
+		},
+		{
+			name:     "Multiple Line Entry",
+			host:     "other.example.com",
+			expected: datafetcher.Credentials{User: "bob", Password: `"secret2"`},
+		},
+		{
+			name:     "Macro Is Skipped",
+			host:     "macro.example.com",
+			expected: datafetcher.Credentials{User: "guest", Password: "guest"},
+		},
+		{
+			name:     "Default Entry",
+			host:     "unknown.example.com",
+			expected: datafetcher.Credentials{User: "guest", Password: "guest"},
+		},
+	}
+
+	provider := datafetcher.NetrcCredentials(path)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			credentials, err := provider.Credentials(context.Background(), tc.host)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if credentials != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, credentials)
+			}
+		})
+	}
+}
+
+// TestNetrcCredentialsMissing tests hosts without an entry and missing files.
+func TestNetrcCredentialsMissing(t *testing.T) {
+	path := writeFile(t, "netrc", "machine t8.example.com login alice password secret\n")
+
+	_, err := datafetcher.NetrcCredentials(path).Credentials(context.Background(), "other")
+	if !errors.Is(err, datafetcher.ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	_, err = datafetcher.NetrcCredentials("").Credentials(context.Background(), "t8.example.com")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+// TestFileProviders tests reading credentials and tokens from files.
+func TestFileProviders(t *testing.T) {
+	credentialsPath := writeFile(t, "credentials", "user:pass:word\n")
+	credentials, err := datafetcher.FileCredentials(credentialsPath).
+		Credentials(context.Background(), "t8.example.com")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := datafetcher.Credentials{User: "user", Password: "pass:word"}
+	if credentials != expected {
+		t.Errorf("expected %+v, got %+v", expected, credentials)
+	}
+
+	malformedPath := writeFile(t, "malformed", "user\n")
+	_, err = datafetcher.FileCredentials(malformedPath).
+		Credentials(context.Background(), "t8.example.com")
+	if !errors.Is(err, datafetcher.ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+
+	tokenPath := writeFile(t, "token", "  secret \nignored\n")
+	token, err := datafetcher.FileToken(tokenPath).Token(context.Background(), "t8.example.com")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "secret" {
+		t.Errorf("expected token %q, got %q", "secret", token)
+	}
+}
+
+// TestEnvProviders tests reading credentials and tokens from environment variables.
+func TestEnvProviders(t *testing.T) {
+	t.Setenv("T8_TEST_USER", "user")
+	t.Setenv("T8_TEST_PASSWORD", "password")
+	t.Setenv("T8_TEST_TOKEN", "token")
+
+	credentials, err := datafetcher.EnvCredentials("T8_TEST_USER", "T8_TEST_PASSWORD").
+		Credentials(context.Background(), "t8.example.com")
+	expected := datafetcher.Credentials{User: "user", Password: "password"}
+	if err != nil || credentials != expected {
+		t.Errorf("expected %+v, got %+v (%v)", expected, credentials, err)
+	}
+
+	token, err := datafetcher.EnvToken("T8_TEST_TOKEN").Token(context.Background(), "")
+	if err != nil || token != "token" {
+		t.Errorf("expected token %q, got %q (%v)", "token", token, err)
+	}
+}
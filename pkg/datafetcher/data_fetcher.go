@@ -1,22 +1,25 @@
 package datafetcher
 
 import (
+	"context"
+	"io"
+
 	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
 	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
 )
 
+// DataFetcher retrieves waveforms and spectra stored in a T8 device. Where the data
+// comes from, and how the device is reached and authenticated against, is up to each
+// implementation.
 type DataFetcher interface {
 	// GetWaveform retrieves waveform data from a remote server.
 	//
 	// Parameters:
 	//   - urlParams: A PmodeUrlTimeParams struct containing the following fields:
-	//       - Host: The base URL of the server.
 	//       - Machine: The machine identifier.
 	//       - Point: The measurement point identifier.
 	//       - Pmode: The processing mode.
-	//       - DateTime: The timestamp for the data request in ISO format.
-	//       - User: The username for authentication.
-	//       - Password: The password for authentication.
+	//       - DateTime or Time: The acquisition time of the record.
 	//
 	// Returns:
 	//   - waveforms.Waveform: A struct containing the decoded waveform data, including samples and sample rate.
@@ -27,16 +30,45 @@ type DataFetcher interface {
 	//
 	// Parameters:
 	//   - urlParams: A PmodeUrlTimeParams struct containing the following fields:
-	//       - Host: The base URL of the server.
 	//       - Machine: The machine identifier.
 	//       - Point: The measurement point identifier.
 	//       - Pmode: The processing mode.
-	//       - DateTime: The timestamp for the data request in ISO format.
-	//       - User: The username for authentication.
-	//       - Password: The password for authentication.
+	//       - DateTime or Time: The acquisition time of the record.
 	//
 	// Returns:
-	//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies and magnitudes.
+	//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies, magnitudes
+	//     and the frequency range (Fmin, Fmax) of the spectrum.
 	//   - error: An error if the request fails, the response cannot be decoded, or any other issue occurs.
 	GetSpectrum(urlParams PmodeUrlTimeParams) (spectra.Spectrum, error)
+
+	// GetWaveformContext behaves like GetWaveform, but binds the request to ctx so that
+	// its deadline and cancellation are propagated to the remote call.
+	GetWaveformContext(
+		ctx context.Context,
+		urlParams PmodeUrlTimeParams,
+	) (waveforms.Waveform, error)
+
+	// GetSpectrumContext behaves like GetSpectrum, but binds the request to ctx so that
+	// its deadline and cancellation are propagated to the remote call.
+	GetSpectrumContext(ctx context.Context, urlParams PmodeUrlTimeParams) (spectra.Spectrum, error)
+}
+
+// RawRecordSource retrieves undecoded T8 record responses, and lists the records it
+// holds. Decorators such as CachingDataFetcher build on it to handle the responses
+// without decoding them.
+type RawRecordSource interface {
+	RecordLister
+
+	// Host returns the address of the T8 device the records come from.
+	Host() string
+
+	// GetRawRecord retrieves the undecoded JSON response of a waveform or spectrum and
+	// hands it to consume. Transient failures may be retried, calling consume again with
+	// a new response, so consume must not keep any state across calls.
+	GetRawRecord(
+		ctx context.Context,
+		kind RecordKind,
+		urlParams PmodeUrlTimeParams,
+		consume func(io.Reader) error,
+	) error
 }
@@ -0,0 +1,174 @@
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Machine describes a machine configured in a T8 device.
+type Machine struct {
+	// Tag is the identifier used in request URLs, e.g. "LP_Turbine".
+	Tag string `json:"tag"`
+	// Name is the human-readable name of the machine.
+	Name string `json:"name"`
+	// Points holds the measurement points of the machine. It is only filled in by
+	// Discover.
+	Points []Point `json:"-"`
+}
+
+// Point describes a measurement point of a machine.
+type Point struct {
+	// Tag is the identifier used in request URLs, e.g. "MAD31CY005".
+	Tag string `json:"tag"`
+	// Name is the human-readable name of the point.
+	Name string `json:"name"`
+	// Units are the physical units of the signal measured at the point, if reported.
+	Units string `json:"units"`
+	// Pmodes holds the processing modes of the point. It is only filled in by Discover.
+	Pmodes []Pmode `json:"-"`
+}
+
+// Pmode describes a processing mode of a measurement point. Numeric fields are zero
+// when the device does not report them.
+type Pmode struct {
+	// Tag is the identifier used in request URLs, e.g. "AM1".
+	Tag string `json:"tag"`
+	// Name is the human-readable name of the processing mode.
+	Name string `json:"name"`
+	// SampleRate is the sample rate of the acquired waveforms, in Hz.
+	SampleRate float64 `json:"sample_rate"`
+	// Samples is the number of samples of each acquired waveform.
+	Samples int `json:"samples"`
+	// Units are the physical units of the processed signal.
+	Units string `json:"units"`
+	// MinFreq is the lower frequency limit of the spectra, in Hz.
+	MinFreq float64 `json:"min_freq"`
+	// MaxFreq is the upper frequency limit of the spectra, in Hz.
+	MaxFreq float64 `json:"max_freq"`
+}
+
+// collectionResponse is the envelope of the T8 configuration listing endpoints.
+type collectionResponse[T any] struct {
+	Items []T `json:"_items"`
+}
+
+// ListMachines retrieves the machines configured in the T8.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//
+// Returns:
+//   - []Machine: The configured machines, without their points.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) ListMachines(ctx context.Context) ([]Machine, error) {
+	return getCollection[Machine](ctx, h, "/machines/")
+}
+
+// ListPoints retrieves the measurement points of a machine.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - machine: The machine identifier.
+//
+// Returns:
+//   - []Point: The points of the machine, without their processing modes.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) ListPoints(ctx context.Context, machine string) ([]Point, error) {
+	return getCollection[Point](
+		ctx,
+		h,
+		fmt.Sprintf("/machines/%s/points/", url.PathEscape(machine)),
+	)
+}
+
+// ListPmodes retrieves the processing modes of a measurement point, including their
+// acquisition settings where the device reports them.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - machine: The machine identifier.
+//   - point: The point identifier.
+//
+// Returns:
+//   - []Pmode: The processing modes of the point.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) ListPmodes(ctx context.Context, machine, point string) ([]Pmode, error) {
+	return getCollection[Pmode](
+		ctx,
+		h,
+		fmt.Sprintf(
+			"/machines/%s/points/%s/pmodes/",
+			url.PathEscape(machine),
+			url.PathEscape(point),
+		),
+	)
+}
+
+// Discover retrieves the whole configuration tree of the T8: every machine, with its
+// points, with their processing modes.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the requests.
+//
+// Returns:
+//   - []Machine: The configured machines, with Points and Pmodes filled in.
+//   - error: An error if any of the requests fails or its response cannot be decoded.
+func (h HttpDataFetcher) Discover(ctx context.Context) ([]Machine, error) {
+	machines, err := h.ListMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range machines {
+		points, err := h.ListPoints(ctx, machines[i].Tag)
+		if err != nil {
+			return nil, fmt.Errorf("error listing points of machine %q: %w", machines[i].Tag, err)
+		}
+
+		for j := range points {
+			pmodes, err := h.ListPmodes(ctx, machines[i].Tag, points[j].Tag)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"error listing processing modes of point %q of machine %q: %w",
+					points[j].Tag,
+					machines[i].Tag,
+					err,
+				)
+			}
+			points[j].Pmodes = pmodes
+		}
+
+		machines[i].Points = points
+	}
+
+	return machines, nil
+}
+
+// getCollection fetches a T8 configuration listing endpoint and decodes its items.
+func getCollection[T any](
+	ctx context.Context,
+	h HttpDataFetcher,
+	collectionPath string,
+) ([]T, error) {
+	if h.client == nil {
+		return nil, errNoClient
+	}
+
+	body, err := h.client.get(ctx, collectionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var response collectionResponse[T]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("%w: JSON response: %w", ErrDecode, err)
+	}
+
+	if response.Items == nil {
+		return []T{}, nil
+	}
+
+	return response.Items, nil
+}
@@ -0,0 +1,149 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newDiscoveryServer creates a mock server exposing a T8 configuration with one
+// machine, one point and two processing modes.
+func newDiscoveryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/machines/": `{"_items": [{"tag": "LP_Turbine", "name": "Low pressure turbine"}]}`,
+		"/machines/LP_Turbine/points/": `{"_items": [
+			{"tag": "MAD31CY005", "name": "Bearing 1", "units": "g"}
+		]}`,
+		"/machines/LP_Turbine/points/MAD31CY005/pmodes/": `{"_items": [
+			{
+				"tag": "AM1",
+				"name": "Acceleration",
+				"sample_rate": 2560,
+				"samples": 2048,
+				"units": "g",
+				"min_freq": 0,
+				"max_freq": 1000
+			},
+			{"tag": "TREND", "name": "Overall"}
+		]}`,
+	}
+
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response, ok := responses[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if _, err := w.Write([]byte(response)); err != nil {
+				t.Errorf("failed to write response: %v", err)
+			}
+		}),
+	)
+}
+
+// TestDiscover tests retrieving the full configuration tree.
+func TestDiscover(t *testing.T) {
+	mock_server := newDiscoveryServer(t)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	machines, err := fetcher.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []datafetcher.Machine{
+		{
+			Tag:  "LP_Turbine",
+			Name: "Low pressure turbine",
+			Points: []datafetcher.Point{
+				{
+					Tag:   "MAD31CY005",
+					Name:  "Bearing 1",
+					Units: "g",
+					Pmodes: []datafetcher.Pmode{
+						{
+							Tag:        "AM1",
+							Name:       "Acceleration",
+							SampleRate: 2560,
+							Samples:    2048,
+							Units:      "g",
+							MinFreq:    0,
+							MaxFreq:    1000,
+						},
+						{Tag: "TREND", Name: "Overall"},
+					},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(machines, expected) {
+		t.Errorf("expected machines %+v, got %+v", expected, machines)
+	}
+}
+
+// TestListMachinesEmpty tests that an empty collection yields an empty, non-nil slice.
+func TestListMachinesEmpty(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := w.Write([]byte(`{"_items": []}`)); err != nil {
+				t.Errorf("failed to write response: %v", err)
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	machines, err := fetcher.ListMachines(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if machines == nil || len(machines) != 0 {
+		t.Errorf("expected empty machine list, got %v", machines)
+	}
+}
+
+// TestListPointsUnknownMachine tests that listing the points of an unknown machine reports ErrNotFound.
+func TestListPointsUnknownMachine(t *testing.T) {
+	mock_server := newDiscoveryServer(t)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	_, err := fetcher.ListPoints(context.Background(), "unknown")
+	if !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestListPmodesInvalidJSON tests that malformed configuration responses are reported as ErrDecode.
+func TestListPmodesInvalidJSON(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := w.Write([]byte("invalid json")); err != nil {
+				t.Errorf("failed to write response: %v", err)
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	_, err := fetcher.ListPmodes(context.Background(), "LP_Turbine", "MAD31CY005")
+	if !errors.Is(err, datafetcher.ErrDecode) {
+		t.Errorf("expected ErrDecode, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package datafetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors reported by the fetchers in this package. They can be matched
+// with errors.Is, regardless of the additional context wrapped around them.
+var (
+	// ErrNotFound is reported when the requested record does not exist, e.g. when
+	// there is no waveform stored at the requested timestamp.
+	ErrNotFound = errors.New("record not found")
+
+	// ErrUnauthorized is reported when the server rejects the credentials, or the
+	// user is not allowed to access the requested resource.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrDecode is reported when a response was received but its payload could not
+	// be decoded, e.g. because of malformed JSON or corrupt sample data.
+	ErrDecode = errors.New("error decoding response")
+
+	// ErrBadTimestamp is reported when the timestamp of a request cannot be parsed.
+	ErrBadTimestamp = errors.New("invalid timestamp")
+
+	// ErrNoCredentials is reported when a credential provider has no credentials for
+	// the requested device.
+	ErrNoCredentials = errors.New("no credentials")
+)
+
+// maxErrorBodySize is the maximum number of bytes of a response body kept in a
+// StatusError.
+const maxErrorBodySize = 512
+
+// StatusError is reported when the server answers with a status code other than
+// 200 OK. It can be inspected with errors.As, and matches ErrNotFound (404) and
+// ErrUnauthorized (401 and 403) with errors.Is.
+type StatusError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// URL is the requested URL. It never contains credentials.
+	URL string
+	// Body holds up to the first 512 bytes of the response body.
+	Body string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+	}
+	return fmt.Sprintf("unexpected status code %d from %s: %s", e.StatusCode, e.URL, e.Body)
+}
+
+// Is reports whether the status code of e corresponds to the target sentinel error.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	default:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package datafetcher_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// TestStatusErrors tests that unexpected status codes are reported as a *StatusError
+// matching the corresponding sentinel errors.
+func TestStatusErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		statusCode   int
+		notFound     bool
+		unauthorized bool
+	}{
+		{name: "Not Found", statusCode: http.StatusNotFound, notFound: true},
+		{name: "Unauthorized", statusCode: http.StatusUnauthorized, unauthorized: true},
+		{name: "Forbidden", statusCode: http.StatusForbidden, unauthorized: true},
+		{name: "Internal Server Error", statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock_server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "something went wrong", tc.statusCode)
+				}),
+			)
+			defer mock_server.Close()
+
+			fetcher := newTestFetcher(t, mock_server.URL)
+			params := datafetcher.NewPmodeUrlTimeParams(
+				"test_machine",
+				"test_point",
+				"test_pmode",
+				"2019-04-10T14:48:44",
+			)
+
+			_, err := fetcher.GetWaveform(params)
+
+			var statusErr *datafetcher.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("expected *StatusError, got %v", err)
+			}
+
+			if statusErr.StatusCode != tc.statusCode {
+				t.Errorf("expected status code %d, got %d", tc.statusCode, statusErr.StatusCode)
+			}
+
+			expectedURL := mock_server.URL + "/waves/test_machine/test_point/test_pmode/1554907724"
+			if statusErr.URL != expectedURL {
+				t.Errorf("expected URL %q, got %q", expectedURL, statusErr.URL)
+			}
+
+			if statusErr.Body != "something went wrong" {
+				t.Errorf("expected body snippet %q, got %q", "something went wrong", statusErr.Body)
+			}
+
+			if errors.Is(err, datafetcher.ErrNotFound) != tc.notFound {
+				t.Errorf("expected errors.Is(err, ErrNotFound) to be %v", tc.notFound)
+			}
+
+			if errors.Is(err, datafetcher.ErrUnauthorized) != tc.unauthorized {
+				t.Errorf("expected errors.Is(err, ErrUnauthorized) to be %v", tc.unauthorized)
+			}
+		})
+	}
+}
+
+// TestStatusErrorBodyTruncated tests that only a snippet of large error bodies is kept.
+func TestStatusErrorBodyTruncated(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, strings.Repeat("x", 10000), http.StatusBadRequest)
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	_, err := fetcher.GetSpectrum(params)
+
+	var statusErr *datafetcher.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected *StatusError, got %v", err)
+	}
+
+	if len(statusErr.Body) != 512 {
+		t.Errorf("expected body snippet of 512 bytes, got %d", len(statusErr.Body))
+	}
+}
+
+// TestDecodeErrors tests that malformed payloads are reported as ErrDecode.
+func TestDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{name: "Invalid JSON", body: "invalid json"},
+		{name: "Empty Response", body: "{}"},
+		{name: "Invalid Data", body: `{"data": "invalid_data"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock_server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					if _, err := w.Write([]byte(tc.body)); err != nil {
+						t.Errorf("failed to write response: %v", err)
+					}
+				}),
+			)
+			defer mock_server.Close()
+
+			fetcher := newTestFetcher(t, mock_server.URL)
+			params := datafetcher.NewPmodeUrlTimeParams(
+				"test_machine",
+				"test_point",
+				"test_pmode",
+				"2019-04-10T14:48:44",
+			)
+
+			if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrDecode) {
+				t.Errorf("expected ErrDecode from GetWaveform, got %v", err)
+			}
+
+			if _, err := fetcher.GetSpectrum(params); !errors.Is(err, datafetcher.ErrDecode) {
+				t.Errorf("expected ErrDecode from GetSpectrum, got %v", err)
+			}
+		})
+	}
+}
+
+// TestBadTimestampError tests that unparseable timestamps are reported as ErrBadTimestamp.
+func TestBadTimestampError(t *testing.T) {
+	fetcher := newTestFetcher(t, "http://127.0.0.1:0")
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"invalid_timestamp",
+	)
+
+	if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrBadTimestamp) {
+		t.Errorf("expected ErrBadTimestamp from GetWaveform, got %v", err)
+	}
+
+	if _, err := fetcher.GetSpectrum(params); !errors.Is(err, datafetcher.ErrBadTimestamp) {
+		t.Errorf("expected ErrBadTimestamp from GetSpectrum, got %v", err)
+	}
+}
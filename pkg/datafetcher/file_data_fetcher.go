@@ -0,0 +1,204 @@
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// FileDataFetcher implements DataFetcher, RecordSource and RawRecordSource.
+var (
+	_ DataFetcher     = FileDataFetcher{}
+	_ RecordSource    = FileDataFetcher{}
+	_ RawRecordSource = FileDataFetcher{}
+)
+
+// FileDataFetcher reads waveforms and spectra from a directory holding the raw JSON
+// responses of a T8 device, e.g. records archived on site, so that they can be analysed
+// without network access. The directory is laid out as
+//
+//	<dir>/<machine>/<point>/<pmode>/<waves|spectra>/<unix timestamp>.json
+//
+// which is also the layout of the per-host directories written by CachingDataFetcher,
+// so a cache directory can be read as an archive.
+type FileDataFetcher struct {
+	dir string
+}
+
+// NewFileDataFetcher creates a FileDataFetcher reading the records stored below dir.
+//
+// Parameters:
+//   - dir: The directory holding the records.
+//
+// Returns:
+//   - FileDataFetcher: The new fetcher.
+//   - error: An error if dir does not exist or is not a directory.
+func NewFileDataFetcher(dir string) (FileDataFetcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return FileDataFetcher{}, fmt.Errorf("error opening record directory: %w", err)
+	}
+	if !info.IsDir() {
+		return FileDataFetcher{}, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return FileDataFetcher{dir: dir}, nil
+}
+
+// Host returns the directory the records are read from.
+func (f FileDataFetcher) Host() string {
+	return f.dir
+}
+
+// GetWaveform reads a waveform from the record directory. See HttpDataFetcher.GetWaveform;
+// a missing record is reported as ErrNotFound.
+func (f FileDataFetcher) GetWaveform(urlParams PmodeUrlTimeParams) (waveforms.Waveform, error) {
+	return f.GetWaveformContext(context.Background(), urlParams)
+}
+
+// GetWaveformContext behaves like GetWaveform. Reading files cannot be cancelled, so
+// ctx is only checked before starting.
+func (f FileDataFetcher) GetWaveformContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (waveforms.Waveform, error) {
+	return fetchWaveform(ctx, f, urlParams, nil)
+}
+
+// GetSpectrum reads a spectrum from the record directory. See HttpDataFetcher.GetSpectrum;
+// a missing record is reported as ErrNotFound.
+func (f FileDataFetcher) GetSpectrum(urlParams PmodeUrlTimeParams) (spectra.Spectrum, error) {
+	return f.GetSpectrumContext(context.Background(), urlParams)
+}
+
+// GetSpectrumContext behaves like GetSpectrum. Reading files cannot be cancelled, so
+// ctx is only checked before starting.
+func (f FileDataFetcher) GetSpectrumContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (spectra.Spectrum, error) {
+	return fetchSpectrum(ctx, f, urlParams, nil)
+}
+
+// GetRawRecord hands the file holding a waveform or spectrum to consume.
+//
+// Parameters:
+//   - ctx: The context checked before opening the file.
+//   - kind: The kind of record to read.
+//   - urlParams: A PmodeUrlTimeParams struct describing the record to read.
+//   - consume: The function reading the record.
+//
+// Returns:
+//
+//	An error matching ErrNotFound if the record is not stored, any other error opening
+//	the file, or the error returned by consume.
+func (f FileDataFetcher) GetRawRecord(
+	ctx context.Context,
+	kind RecordKind,
+	urlParams PmodeUrlTimeParams,
+	consume func(io.Reader) error,
+) error {
+	acquired, err := recordTime(urlParams)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := kindDirs[kind]; !ok {
+		return fmt.Errorf("unsupported record kind %s", kind)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := recordFile(f.dir, kind, urlParams.PmodeUrlParams, acquired)
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return fmt.Errorf("error opening record: %w", err)
+	}
+	defer file.Close()
+
+	return consume(file)
+}
+
+// ListWaveforms returns the acquisition times of the waveforms stored in the record
+// directory for a processing mode within timeRange, in UTC and ascending order. A
+// processing mode without a waveform directory is reported as ErrNotFound.
+func (f FileDataFetcher) ListWaveforms(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return f.listRecords(ctx, WaveformRecord, urlParams, timeRange)
+}
+
+// ListSpectra returns the acquisition times of the spectra stored in the record
+// directory for a processing mode within timeRange, in UTC and ascending order. A
+// processing mode without a spectrum directory is reported as ErrNotFound.
+func (f FileDataFetcher) ListSpectra(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return f.listRecords(ctx, SpectrumRecord, urlParams, timeRange)
+}
+
+// listRecords returns the timestamps of the records of the given kind stored for a
+// processing mode that fall within timeRange, sorted in ascending order. Files not
+// named after a timestamp are ignored.
+func (f FileDataFetcher) listRecords(
+	ctx context.Context,
+	kind RecordKind,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dir := recordDir(f.dir, kind, urlParams)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading record directory: %w", err)
+	}
+
+	times := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), recordFileExt)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		t := time.Unix(timestamp, 0).UTC()
+		if timeRange.Contains(t) {
+			times = append(times, t)
+		}
+	}
+
+	slices.SortFunc(times, time.Time.Compare)
+
+	return times, nil
+}
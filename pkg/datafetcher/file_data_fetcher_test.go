@@ -0,0 +1,204 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// writeRecord stores a record document below dir, creating its directory.
+func writeRecord(t *testing.T, dir string, path []string, document string) {
+	t.Helper()
+
+	file := filepath.Join(append([]string{dir}, path...)...)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatalf("failed to create record directory: %v", err)
+	}
+	if err := os.WriteFile(file, []byte(document), 0o644); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+}
+
+// newArchive creates a record directory holding a few waveforms and a spectrum.
+func newArchive(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	waveform := `{"data": "eJxjZPj//389QwMAEP4D/g==", "factor": 2, "sample_rate": 2560, "units": "g"}`
+	for _, name := range []string{"1554907724.json", "1554907784.json", "1554907844.json"} {
+		writeRecord(t, dir, []string{"machine", "point", "pmode", "waves", name}, waveform)
+	}
+	writeRecord(
+		t,
+		dir,
+		[]string{"machine", "point", "pmode", "waves", "notes.txt"},
+		"not a record",
+	)
+	writeRecord(
+		t,
+		dir,
+		[]string{"machine", "point", "pmode", "spectra", "1554907724.json"},
+		`{"data": "eJxjZPj//389QwMAEP4D/g==", "factor": 1, "min_freq": 0, "max_freq": 300}`,
+	)
+
+	return dir
+}
+
+// TestFileDataFetcherGetRecords tests reading waveforms and spectra from a directory.
+func TestFileDataFetcherGetRecords(t *testing.T) {
+	fetcher, err := datafetcher.NewFileDataFetcher(newArchive(t))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+	source := metadata.Metadata{
+		Machine: "machine",
+		Point:   "point",
+		Pmode:   "pmode",
+		Time:    time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC),
+	}
+
+	waveform, err := fetcher.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedMetadata := source
+	expectedMetadata.Factor = 2
+	expectedMetadata.Units = "g"
+	expectedWaveform := waveforms.Waveform{
+		Metadata:   expectedMetadata,
+		Samples:    []float64{2, -2, 65534, -65536},
+		SampleRate: 2560,
+	}
+	if !reflect.DeepEqual(waveform, expectedWaveform) {
+		t.Errorf("expected waveform %+v, got %+v", expectedWaveform, waveform)
+	}
+
+	spectrum, err := fetcher.GetSpectrum(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedSpectrum := spectra.NewSpectrum([]float64{1, -1, 32767, -32768}, 0, 300)
+	expectedSpectrum.Metadata = source
+	expectedSpectrum.Factor = 1
+	if !reflect.DeepEqual(spectrum, expectedSpectrum) {
+		t.Errorf("expected spectrum %+v, got %+v", expectedSpectrum, spectrum)
+	}
+
+	missing := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:49:44")
+	if _, err := fetcher.GetSpectrum(missing); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestFileDataFetcherListRecords tests listing the records stored in a directory.
+func TestFileDataFetcherListRecords(t *testing.T) {
+	fetcher, err := datafetcher.NewFileDataFetcher(newArchive(t))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	params := datafetcher.NewPmodeUrlParams("machine", "point", "pmode")
+
+	times, err := fetcher.ListWaveforms(
+		context.Background(),
+		params,
+		datafetcher.TimeRange{From: time.Unix(1554907784, 0)},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []time.Time{time.Unix(1554907784, 0).UTC(), time.Unix(1554907844, 0).UTC()}
+	if !reflect.DeepEqual(times, expected) {
+		t.Errorf("expected times %v, got %v", expected, times)
+	}
+
+	_, err = fetcher.ListSpectra(
+		context.Background(),
+		datafetcher.NewPmodeUrlParams("machine", "point", "other"),
+		datafetcher.TimeRange{},
+	)
+	if !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestFileDataFetcherReadsCache tests that a host directory of a cache can be read as
+// an archive once the device is gone.
+func TestFileDataFetcherReadsCache(t *testing.T) {
+	var counter requestCounter
+	mock_server := newCacheServer(t, &counter)
+	defer mock_server.Close()
+
+	dir := t.TempDir()
+	cache := newTestCache(t, mock_server.URL, dir)
+
+	params := datafetcher.NewPmodeUrlTimeParams(
+		"test/machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	expected, err := cache.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	mock_server.Close()
+
+	hosts, err := os.ReadDir(dir)
+	if err != nil || len(hosts) != 1 {
+		t.Fatalf("expected a single host directory, got %v (%v)", hosts, err)
+	}
+
+	fetcher, err := datafetcher.NewFileDataFetcher(filepath.Join(dir, hosts[0].Name()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	waveform, err := fetcher.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(waveform, expected) {
+		t.Errorf("expected waveform %+v, got %+v", expected, waveform)
+	}
+
+	times, err := fetcher.ListWaveforms(
+		context.Background(),
+		params.PmodeUrlParams,
+		datafetcher.TimeRange{},
+	)
+	if err != nil || len(times) != 1 {
+		t.Errorf("expected a single waveform, got %v (%v)", times, err)
+	}
+}
+
+// TestNewFileDataFetcherInvalidDirectory tests that the record directory must exist.
+func TestNewFileDataFetcherInvalidDirectory(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.json")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing"), file} {
+		if _, err := datafetcher.NewFileDataFetcher(path); err == nil {
+			t.Errorf("expected error for %s, got none", path)
+		}
+	}
+}
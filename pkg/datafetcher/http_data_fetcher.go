@@ -1,190 +1,305 @@
 package datafetcher
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"time"
 
-	"github.com/Daniel-C-R/t8-client-go/internal/decoder"
-	"github.com/Daniel-C-R/t8-client-go/internal/timeconversion"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
 	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/timeconversion"
 	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
-	"gonum.org/v1/gonum/floats"
 )
 
-type HttpDataFetcher struct{}
+// errNoClient is returned by the fetch methods of an HttpDataFetcher that was not
+// created with NewHttpDataFetcher.
+var errNoClient = errors.New("HttpDataFetcher has no client, create it with NewHttpDataFetcher")
+
+// HttpDataFetcher implements DataFetcher and RawRecordSource.
+var (
+	_ DataFetcher     = HttpDataFetcher{}
+	_ RawRecordSource = HttpDataFetcher{}
+)
+
+// HttpDataFetcher retrieves waveforms and spectra from the REST API of a T8 device
+// through a Client.
+type HttpDataFetcher struct {
+	client *Client
+}
+
+// NewHttpDataFetcher creates an HttpDataFetcher that performs its requests through
+// the given client.
+//
+// Parameters:
+//   - client: The Client holding the host, credentials and HTTP settings to use.
+//
+// Returns:
+//
+//	An HttpDataFetcher bound to the given client.
+func NewHttpDataFetcher(client *Client) HttpDataFetcher {
+	return HttpDataFetcher{client: client}
+}
 
 type WaveformResponse struct {
-	RawWaveform string  `json:"data"`
-	Factor      float64 `json:"factor"`
-	SampleRate  float64 `json:"sample_rate"`
+	RawWaveform string   `json:"data"`
+	Factor      float64  `json:"factor"`
+	SampleRate  float64  `json:"sample_rate"`
+	Units       string   `json:"units,omitempty"`
+	Speed       *float64 `json:"speed,omitempty"`
 }
 
+// waveformMembers are the members of a waveform response mapped to WaveformResponse.
+var waveformMembers = []string{"data", "factor", "sample_rate", "units", "speed"}
+
 // GetWaveform retrieves waveform data from a remote server.
 //
 // Parameters:
 //   - urlParams: A PmodeUrlTimeParams struct containing the following fields:
-//   - Host: The base URL of the server.
 //   - Machine: The machine identifier.
 //   - Point: The measurement point identifier.
 //   - Pmode: The processing mode.
-//   - DateTime: The timestamp for the data request in ISO format.
-//   - User: The username for authentication.
-//   - Password: The password for authentication.
+//   - DateTime or Time: The acquisition time of the record.
 //
 // Returns:
-//   - waveforms.Waveform: A struct containing the decoded waveform data, including samples and sample rate.
+//   - waveforms.Waveform: A struct containing the decoded waveform data, including samples, sample rate
+//     and the metadata of the record.
 //   - error: An error if the request fails, the response cannot be decoded, or any other issue occurs.
+//     It matches ErrBadTimestamp, ErrNotFound, ErrUnauthorized or ErrDecode with errors.Is when
+//     applicable, and any unexpected status code can be inspected as a *StatusError with errors.As.
 func (h HttpDataFetcher) GetWaveform(urlParams PmodeUrlTimeParams) (waveforms.Waveform, error) {
-	timestamp, err := timeconversion.IsoStringToTimestamp(urlParams.DateTime)
-	if err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error parsing timestamp: %w", err)
-	}
-
-	url := fmt.Sprintf(
-		"%s/waves/%s/%s/%s/%d",
-		urlParams.Host,
-		urlParams.Machine,
-		urlParams.Point,
-		urlParams.Pmode,
-		timestamp,
-	)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.SetBasicAuth(urlParams.User, urlParams.Password)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error making GET request: %w", err)
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("error closing response body: %v\n", err)
-		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return waveforms.Waveform{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	var waveformResponse WaveformResponse
-	if err := json.Unmarshal(body, &waveformResponse); err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error decoding JSON response: %w", err)
-	}
-
-	samples, err := decoder.ZintToFloat(waveformResponse.RawWaveform)
-	if err != nil {
-		return waveforms.Waveform{}, fmt.Errorf("error decoding waveform data: %w", err)
-	}
-
-	floats.Scale(waveformResponse.Factor, samples)
-
-	waveform := waveforms.Waveform{Samples: samples, SampleRate: waveformResponse.SampleRate}
+	return h.GetWaveformContext(context.Background(), urlParams)
+}
 
-	return waveform, nil
+// GetWaveformContext retrieves waveform data from a remote server, like GetWaveform,
+// but binds the request to the given context. Deadlines and cancellation of ctx are
+// propagated to the underlying HTTP request, so a slow server can be abandoned.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - urlParams: A PmodeUrlTimeParams struct describing the waveform to fetch.
+//
+// Returns:
+//   - waveforms.Waveform: A struct containing the decoded waveform data, including samples, sample rate
+//     and the metadata of the record.
+//   - error: An error if the request fails or is cancelled, the response cannot be decoded, or any other
+//     issue occurs.
+func (h HttpDataFetcher) GetWaveformContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (waveforms.Waveform, error) {
+	return fetchWaveform(ctx, h, urlParams, h.observeDecode)
 }
 
 type SpectrumResponse struct {
-	RawSpectrum string  `json:"data"`
-	Factor      float64 `json:"factor"`
-	Fmin        float64 `json:"min_freq"`
-	Fmax        float64 `json:"max_freq"`
+	RawSpectrum string   `json:"data"`
+	Factor      float64  `json:"factor"`
+	Fmin        float64  `json:"min_freq"`
+	Fmax        float64  `json:"max_freq"`
+	Units       string   `json:"units,omitempty"`
+	Speed       *float64 `json:"speed,omitempty"`
 }
 
+// spectrumMembers are the members of a spectrum response mapped to SpectrumResponse.
+var spectrumMembers = []string{"data", "factor", "min_freq", "max_freq", "units", "speed"}
+
 // GetSpectrum retrieves spectrum data from a remote server.
 //
 // Parameters:
 //   - urlParams: A PmodeUrlTimeParams struct containing the following fields:
-//   - Host: The base URL of the server.
 //   - Machine: The machine identifier.
 //   - Point: The measurement point identifier.
 //   - Pmode: The processing mode.
-//   - DateTime: The timestamp for the data request in ISO format.
-//   - User: The username for authentication.
-//   - Password: The password for authentication.
+//   - DateTime or Time: The acquisition time of the record.
 //
 // Returns:
-//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies and magnitudes.
-//   - float64: The minimum frequency of the spectrum.
-//   - float64: The maximum frequency of the spectrum.
+//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies, magnitudes,
+//     the frequency range reported by the server and the metadata of the record.
 //   - error: An error if the request fails, the response cannot be decoded, or any other issue occurs.
-func (h HttpDataFetcher) GetSpectrum(
+//     It matches ErrBadTimestamp, ErrNotFound, ErrUnauthorized or ErrDecode with errors.Is when
+//     applicable, and any unexpected status code can be inspected as a *StatusError with errors.As.
+func (h HttpDataFetcher) GetSpectrum(urlParams PmodeUrlTimeParams) (spectra.Spectrum, error) {
+	return h.GetSpectrumContext(context.Background(), urlParams)
+}
+
+// GetSpectrumContext retrieves spectrum data from a remote server, like GetSpectrum,
+// but binds the request to the given context. Deadlines and cancellation of ctx are
+// propagated to the underlying HTTP request, so a slow server can be abandoned.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - urlParams: A PmodeUrlTimeParams struct describing the spectrum to fetch.
+//
+// Returns:
+//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies, magnitudes,
+//     the frequency range reported by the server and the metadata of the record.
+//   - error: An error if the request fails or is cancelled, the response cannot be decoded, or any other
+//     issue occurs.
+func (h HttpDataFetcher) GetSpectrumContext(
+	ctx context.Context,
+	urlParams PmodeUrlTimeParams,
+) (spectra.Spectrum, error) {
+	return fetchSpectrum(ctx, h, urlParams, h.observeDecode)
+}
+
+// GetRawRecord retrieves the undecoded JSON response of a waveform or spectrum from a
+// remote server, and hands it to consume as it is received. Transient failures are
+// retried, calling consume again with the new response, so consume must not keep any
+// state across calls.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - kind: The kind of record to fetch.
+//   - urlParams: A PmodeUrlTimeParams struct describing the record to fetch.
+//   - consume: The function reading the response.
+//
+// Returns:
+//
+//	An error if the request fails or is cancelled, or the error returned by consume.
+func (h HttpDataFetcher) GetRawRecord(
+	ctx context.Context,
+	kind RecordKind,
 	urlParams PmodeUrlTimeParams,
-) (spectra.Spectrum, float64, float64, error) {
-	timestamp, err := timeconversion.IsoStringToTimestamp(urlParams.DateTime)
+	consume func(io.Reader) error,
+) error {
+	acquired, err := recordTime(urlParams)
 	if err != nil {
-		return spectra.Spectrum{}, 0, 0, err
+		return err
 	}
 
-	url := fmt.Sprintf(
-		"%s/spectra/%s/%s/%s/%d",
-		urlParams.Host,
-		urlParams.Machine,
-		urlParams.Point,
-		urlParams.Pmode,
-		timestamp,
-	)
+	if h.client == nil {
+		return errNoClient
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("error creating request: %w", err)
+	var endpoint string
+	switch kind {
+	case WaveformRecord:
+		endpoint = "/waves"
+	case SpectrumRecord:
+		endpoint = "/spectra"
+	default:
+		return fmt.Errorf("unsupported record kind %s", kind)
 	}
 
-	req.SetBasicAuth(urlParams.User, urlParams.Password)
+	path := fmt.Sprintf("%s%s/%d", endpoint, urlParams.path(), acquired.Unix())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("error making GET request: %w", err)
+	return h.client.getStream(ctx, path, consume)
+}
+
+// Host returns the host of the client the fetcher performs its requests through, or ""
+// if it has none.
+func (h HttpDataFetcher) Host() string {
+	if h.client == nil {
+		return ""
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("error closing response body: %v\n", err)
-		}
-	}()
+	return h.client.Host()
+}
+
+// observeDecode reports stats through the client of the fetcher, if it has one.
+func (h HttpDataFetcher) observeDecode(ctx context.Context, stats DecodeStats) {
+	if h.client != nil {
+		h.client.observeDecode(ctx, stats)
+	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// fetchWaveform retrieves a waveform from source and decodes it, reporting the decoding
+// to observe unless it is nil.
+func fetchWaveform(
+	ctx context.Context,
+	source RawRecordSource,
+	urlParams PmodeUrlTimeParams,
+	observe func(context.Context, DecodeStats),
+) (waveforms.Waveform, error) {
+	acquired, err := recordTime(urlParams)
+	if err != nil {
+		return waveforms.Waveform{}, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	var waveform waveforms.Waveform
+	err = source.GetRawRecord(ctx, WaveformRecord, urlParams, func(r io.Reader) error {
+		start := time.Now()
+		decoded, err := DecodeWaveform(r)
+		if observe != nil {
+			observe(ctx, DecodeStats{
+				Kind:     WaveformRecord,
+				Duration: time.Since(start),
+				Samples:  len(decoded.Samples),
+				Err:      err,
+			})
+		}
+		waveform = decoded
+		return err
+	})
 	if err != nil {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("error reading response body: %w", err)
+		return waveforms.Waveform{}, err
 	}
 
-	var spectrumResponse SpectrumResponse
-	if err := json.Unmarshal(body, &spectrumResponse); err != nil {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("error decoding JSON response: %w", err)
+	setSource(&waveform.Metadata, urlParams.PmodeUrlParams, acquired)
+
+	return waveform, nil
+}
+
+// fetchSpectrum retrieves a spectrum from source and decodes it, reporting the decoding
+// to observe unless it is nil.
+func fetchSpectrum(
+	ctx context.Context,
+	source RawRecordSource,
+	urlParams PmodeUrlTimeParams,
+	observe func(context.Context, DecodeStats),
+) (spectra.Spectrum, error) {
+	acquired, err := recordTime(urlParams)
+	if err != nil {
+		return spectra.Spectrum{}, err
 	}
 
-	spectrum, err := decoder.ZintToFloat(spectrumResponse.RawSpectrum)
+	var spectrum spectra.Spectrum
+	err = source.GetRawRecord(ctx, SpectrumRecord, urlParams, func(r io.Reader) error {
+		start := time.Now()
+		decoded, err := DecodeSpectrum(r)
+		if observe != nil {
+			observe(ctx, DecodeStats{
+				Kind:     SpectrumRecord,
+				Duration: time.Since(start),
+				Samples:  len(decoded.Magnitudes),
+				Err:      err,
+			})
+		}
+		spectrum = decoded
+		return err
+	})
 	if err != nil {
-		return spectra.Spectrum{}, 0, 0, fmt.Errorf("error decoding spectrum data: %w", err)
+		return spectra.Spectrum{}, err
 	}
 
-	floats.Scale(spectrumResponse.Factor, spectrum)
+	setSource(&spectrum.Metadata, urlParams.PmodeUrlParams, acquired)
 
-	frequencies := make([]float64, len(spectrum))
-	step := (spectrumResponse.Fmax - spectrumResponse.Fmin) / float64(len(spectrum)-1)
-	for i := range frequencies {
-		frequencies[i] = spectrumResponse.Fmin + step*float64(i)
-	}
+	return spectrum, nil
+}
 
-	spectrumObject := spectra.Spectrum{
-		Frequencies: frequencies,
-		Magnitudes:  spectrum,
+// recordTime returns the acquisition time of the record described by urlParams, in UTC
+// and truncated to the second records are identified by.
+func recordTime(urlParams PmodeUrlTimeParams) (time.Time, error) {
+	acquired := urlParams.Time
+	if acquired.IsZero() {
+		var err error
+		acquired, err = timeconversion.ParseTime(
+			urlParams.DateTime,
+			urlParams.Location,
+			time.Now(),
+		)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %w", ErrBadTimestamp, err)
+		}
 	}
+	return time.Unix(acquired.Unix(), 0).UTC(), nil
+}
 
-	return spectrumObject, spectrumResponse.Fmin, spectrumResponse.Fmax, nil
+// setSource records in m the machine, point and processing mode of urlParams and the
+// acquisition time of the record.
+func setSource(m *metadata.Metadata, urlParams PmodeUrlParams, acquired time.Time) {
+	m.Machine = urlParams.Machine
+	m.Point = urlParams.Point
+	m.Pmode = urlParams.Pmode
+	m.Time = acquired
 }
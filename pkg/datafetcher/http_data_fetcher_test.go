@@ -1,27 +1,55 @@
 package datafetcher_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
 	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
 	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
 	"gonum.org/v1/gonum/floats"
 )
 
+// newTestFetcher creates an HttpDataFetcher pointing at the given mock server URL.
+func newTestFetcher(t *testing.T, url string) datafetcher.HttpDataFetcher {
+	t.Helper()
+
+	client, err := datafetcher.NewClient(url, datafetcher.WithCredentials("user", "password"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return datafetcher.NewHttpDataFetcher(client)
+}
+
 // TestGetWaveformSuccess tests the successful retrieval of a waveform.
 func TestGetWaveformSuccess(t *testing.T) {
+	speed := 24.5
 	mockWaveformResponse := datafetcher.WaveformResponse{
 		RawWaveform: "eJxjZPj//389QwMAEP4D/g==",
 		Factor:      2.0,
 		SampleRate:  2560,
+		Units:       "g",
+		Speed:       &speed,
 	}
 
 	expectedWaveform := waveforms.Waveform{
+		Metadata: metadata.Metadata{
+			Machine: "test_machine",
+			Point:   "test_point",
+			Pmode:   "test_pmode",
+			Time:    time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC),
+			Factor:  2.0,
+			Units:   "g",
+			Speed:   &speed,
+		},
 		Samples:    []float64{1, -1, 3.2767e04, -3.2768e04},
 		SampleRate: 2560,
 	}
@@ -38,16 +66,13 @@ func TestGetWaveformSuccess(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 	if err != nil {
@@ -59,6 +84,88 @@ func TestGetWaveformSuccess(t *testing.T) {
 	}
 }
 
+// TestGetWaveformRecordTime tests that every way of giving the acquisition time requests
+// the same record.
+func TestGetWaveformRecordTime(t *testing.T) {
+	acquired := time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+	expectedPath := "/waves/test_machine/test_point/test_pmode/1554907724"
+
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != expectedPath {
+				http.NotFound(w, r)
+				return
+			}
+			response := datafetcher.WaveformResponse{
+				RawWaveform: "eJxjZPj//389QwMAEP4D/g==",
+				Factor:      1,
+				SampleRate:  2560,
+			}
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	testCases := []struct {
+		name   string
+		params datafetcher.PmodeUrlTimeParams
+	}{
+		{
+			name: "Naive DateTime",
+			params: datafetcher.NewPmodeUrlTimeParams(
+				"test_machine", "test_point", "test_pmode", "2019-04-10T14:48:44",
+			),
+		},
+		{
+			name: "DateTime With Offset",
+			params: datafetcher.NewPmodeUrlTimeParams(
+				"test_machine", "test_point", "test_pmode", "2019-04-10T16:48:44+02:00",
+			),
+		},
+		{
+			name: "Naive DateTime In Location",
+			params: datafetcher.PmodeUrlTimeParams{
+				PmodeUrlParams: datafetcher.NewPmodeUrlParams(
+					"test_machine", "test_point", "test_pmode",
+				),
+				DateTime: "2019-04-10T16:48:44",
+				Location: time.FixedZone("CEST", 2*60*60),
+			},
+		},
+		{
+			name: "Unix DateTime",
+			params: datafetcher.NewPmodeUrlTimeParams(
+				"test_machine", "test_point", "test_pmode", "1554907724",
+			),
+		},
+		{
+			name: "Time In Other Location",
+			params: datafetcher.NewPmodeUrlTimeParamsAt(
+				"test_machine",
+				"test_point",
+				"test_pmode",
+				acquired.In(time.FixedZone("EDT", -4*60*60)).Add(300*time.Millisecond),
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			waveform, err := fetcher.GetWaveform(tc.params)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if waveform.Time != acquired {
+				t.Errorf("expected time %v, got %v", acquired, waveform.Time)
+			}
+		})
+	}
+}
+
 // TestGetWaveformFailure tests the failure case when the server returns an error.
 func TestGetWaveformFailure(t *testing.T) {
 	mock_server := httptest.NewServer(
@@ -69,16 +176,13 @@ func TestGetWaveformFailure(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 
@@ -104,16 +208,13 @@ func TestGetWaveformInvalidJSON(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 
@@ -139,16 +240,13 @@ func TestGetWaveformInvalidTimestamp(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"invalid_timestamp",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 
@@ -174,16 +272,13 @@ func TestGetWaveformEmptyResponse(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 
@@ -213,16 +308,13 @@ func TestGetWaveformInvalidWaveformData(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
 	waveform, err := fetcher.GetWaveform(mockPmodeTimeParams)
 
@@ -251,6 +343,13 @@ func TestGetSpectrumSuccess(t *testing.T) {
 		mockSpectrumResponse.Fmin,
 		mockSpectrumResponse.Fmax,
 	)
+	expectedSpectrum.Metadata = metadata.Metadata{
+		Machine: "test_machine",
+		Point:   "test_point",
+		Pmode:   "test_pmode",
+		Time:    time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC),
+		Factor:  2.0,
+	}
 
 	mock_server := httptest.NewServer(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -263,18 +362,15 @@ func TestGetSpectrumSuccess(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -283,12 +379,12 @@ func TestGetSpectrumSuccess(t *testing.T) {
 		t.Errorf("expected spectrum %v, got %v", expectedSpectrum, spectrum)
 	}
 
-	if fmin != mockSpectrumResponse.Fmin {
-		t.Errorf("expected fmin %v, got %v", mockSpectrumResponse.Fmin, fmin)
+	if spectrum.Fmin != mockSpectrumResponse.Fmin {
+		t.Errorf("expected fmin %v, got %v", mockSpectrumResponse.Fmin, spectrum.Fmin)
 	}
 
-	if fmax != mockSpectrumResponse.Fmax {
-		t.Errorf("expected fmax %v, got %v", mockSpectrumResponse.Fmax, fmax)
+	if spectrum.Fmax != mockSpectrumResponse.Fmax {
+		t.Errorf("expected fmax %v, got %v", mockSpectrumResponse.Fmax, spectrum.Fmax)
 	}
 }
 
@@ -302,18 +398,15 @@ func TestGetSpectrumFailure(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 
 	if err == nil {
 		t.Fatalf("expected error, got none")
@@ -322,14 +415,6 @@ func TestGetSpectrumFailure(t *testing.T) {
 	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
 		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
-
-	if fmin != 0 {
-		t.Errorf("expected fmin 0, got %v", fmin)
-	}
-
-	if fmax != 0 {
-		t.Errorf("expected fmax 0, got %v", fmax)
-	}
 }
 
 // TestGetSpectrumInvalidJSON tests the case when the server returns invalid JSON.
@@ -345,18 +430,15 @@ func TestGetSpectrumInvalidJSON(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 
 	if err == nil {
 		t.Fatalf("expected error, got none")
@@ -365,14 +447,6 @@ func TestGetSpectrumInvalidJSON(t *testing.T) {
 	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
 		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
-
-	if fmin != 0 {
-		t.Errorf("expected fmin 0, got %v", fmin)
-	}
-
-	if fmax != 0 {
-		t.Errorf("expected fmax 0, got %v", fmax)
-	}
 }
 
 // TestGetSpectrumInvalidTimestamp tests the case when an invalid timestamp is provided.
@@ -388,18 +462,15 @@ func TestGetSpectrumInvalidTimestamp(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"invalid_timestamp",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 
 	if err == nil {
 		t.Fatalf("expected error, got none")
@@ -408,14 +479,6 @@ func TestGetSpectrumInvalidTimestamp(t *testing.T) {
 	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
 		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
-
-	if fmin != 0 {
-		t.Errorf("expected fmin 0, got %v", fmin)
-	}
-
-	if fmax != 0 {
-		t.Errorf("expected fmax 0, got %v", fmax)
-	}
 }
 
 // TestGetSpectrumEmptyResponse tests the case when the server returns an empty response.
@@ -431,18 +494,15 @@ func TestGetSpectrumEmptyResponse(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 
 	if err == nil {
 		t.Fatalf("expected error, got none")
@@ -451,14 +511,6 @@ func TestGetSpectrumEmptyResponse(t *testing.T) {
 	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
 		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
-
-	if fmin != 0 {
-		t.Errorf("expected fmin 0, got %v", fmin)
-	}
-
-	if fmax != 0 {
-		t.Errorf("expected fmax 0, got %v", fmax)
-	}
 }
 
 // TestGetSpectrumInvalidSpectrumData tests the case when the server returns invalid spectrum data.
@@ -478,18 +530,15 @@ func TestGetSpectrumInvalidSpectrumData(t *testing.T) {
 	defer mock_server.Close()
 
 	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
-		mock_server.URL,
 		"test_machine",
 		"test_point",
 		"test_pmode",
 		"2019-04-10T14:48:44",
-		"user",
-		"password",
 	)
 
-	fetcher := datafetcher.HttpDataFetcher{}
+	fetcher := newTestFetcher(t, mock_server.URL)
 
-	spectrum, fmin, fmax, err := fetcher.GetSpectrum(mockPmodeTimeParams)
+	spectrum, err := fetcher.GetSpectrum(mockPmodeTimeParams)
 
 	if err == nil {
 		t.Fatalf("expected error, got none")
@@ -498,12 +547,73 @@ func TestGetSpectrumInvalidSpectrumData(t *testing.T) {
 	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
 		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
+}
 
-	if fmin != 0 {
-		t.Errorf("expected fmin 0, got %v", fmin)
+// TestGetWaveformContextCancelled tests that a cancelled context aborts the request.
+func TestGetWaveformContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}),
+	)
+	defer mock_server.Close()
+	defer close(release)
+
+	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	waveform, err := fetcher.GetWaveformContext(ctx, mockPmodeTimeParams)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(waveform, waveforms.Waveform{}) {
+		t.Errorf("expected empty waveform, got %v", waveform)
+	}
+}
+
+// TestGetSpectrumContextCancelled tests that a cancelled context aborts the request.
+func TestGetSpectrumContextCancelled(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("request should not reach the server")
+		}),
+	)
+	defer mock_server.Close()
+
+	mockPmodeTimeParams := datafetcher.NewPmodeUrlTimeParams(
+		"test_machine",
+		"test_point",
+		"test_pmode",
+		"2019-04-10T14:48:44",
+	)
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	spectrum, err := fetcher.GetSpectrumContext(ctx, mockPmodeTimeParams)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled error, got %v", err)
 	}
 
-	if fmax != 0 {
-		t.Errorf("expected fmax 0, got %v", fmax)
+	if !reflect.DeepEqual(spectrum, spectra.Spectrum{}) {
+		t.Errorf("expected empty spectrum, got %v", spectrum)
 	}
 }
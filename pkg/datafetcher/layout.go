@@ -0,0 +1,79 @@
+package datafetcher
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Records are stored on disk by CachingDataFetcher, and read by FileDataFetcher, as
+// their raw JSON responses, laid out as
+//
+//	<root>/<host>/<machine>/<point>/<pmode>/<waves|spectra>/<unix timestamp>.json
+//
+// where every path segment is escaped so that it can hold any tag. FileDataFetcher
+// reads the directory of a single host.
+
+// recordFileExt is the extension of the files holding records on disk.
+const recordFileExt = ".json"
+
+// hostDir returns the directory holding the records of host below root. The scheme of
+// host is left out, so that records are shared between HTTP and HTTPS access to a device.
+func hostDir(root, host string) string {
+	name := host
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		name = u.Host + strings.TrimSuffix(u.Path, "/")
+	}
+
+	return filepath.Join(root, pathSegment(name))
+}
+
+// recordDir returns the directory holding the records of the given kind of a processing
+// mode below the directory of a host.
+func recordDir(dir string, kind RecordKind, urlParams PmodeUrlParams) string {
+	return filepath.Join(
+		dir,
+		pathSegment(urlParams.Machine),
+		pathSegment(urlParams.Point),
+		pathSegment(urlParams.Pmode),
+		kindDirs[kind],
+	)
+}
+
+// recordFile returns the file holding a record below the directory of a host.
+func recordFile(
+	dir string,
+	kind RecordKind,
+	urlParams PmodeUrlParams,
+	acquired time.Time,
+) string {
+	return filepath.Join(
+		recordDir(dir, kind, urlParams),
+		fmt.Sprintf("%d%s", acquired.Unix(), recordFileExt),
+	)
+}
+
+// kindDirs are the names of the directories holding each kind of record, which match
+// the T8 API endpoints.
+var kindDirs = map[RecordKind]string{
+	WaveformRecord: "waves",
+	SpectrumRecord: "spectra",
+}
+
+// pathSegment escapes s so that it can be used as a single file name on any platform.
+func pathSegment(s string) string {
+	// PathEscape leaves colons alone, which are not allowed in file names on Windows.
+	segment := strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+
+	switch segment {
+	case "":
+		// Empty names cannot be used, and no escaped name is "%".
+		return "%"
+	case ".", "..":
+		return strings.ReplaceAll(segment, ".", "%2E")
+	default:
+		return segment
+	}
+}
@@ -0,0 +1,170 @@
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits bounds the load a Client puts on a T8 device.
+type Limits struct {
+	// Rate is the sustained number of requests per second. Zero means no rate limit.
+	Rate float64
+	// Burst is the number of requests that can be sent at once, above Rate, after a
+	// quiet period. Zero means 1. It is ignored without a Rate.
+	Burst int
+	// MaxInFlight is the maximum number of requests in progress at the same time,
+	// including the reading of their responses. Zero means no limit.
+	MaxInFlight int
+}
+
+// Limiter enforces Limits on the requests made to a T8 device: a token bucket bounds
+// their rate and a semaphore bounds how many are in progress. Every attempt of a
+// request, including retries, is limited.
+//
+// A Limiter is set on a Client with WithLimiter, and can be shared by several Clients
+// talking to the same device so that their requests are limited together. It is safe
+// for concurrent use by multiple goroutines.
+type Limiter struct {
+	rate  float64
+	burst float64
+	slots chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter enforcing limits. The token bucket starts full.
+//
+// Parameters:
+//   - limits: The rate, burst and maximum number of requests in flight.
+//
+// Returns:
+//   - *Limiter: The limiter.
+//   - error: An error if any limit is negative or not finite.
+func NewLimiter(limits Limits) (*Limiter, error) {
+	if limits.Rate < 0 || math.IsNaN(limits.Rate) || math.IsInf(limits.Rate, 0) {
+		return nil, errors.New("rate limit must be a finite, non-negative number")
+	}
+	if limits.Burst < 0 || limits.MaxInFlight < 0 {
+		return nil, errors.New("burst and maximum requests in flight must not be negative")
+	}
+
+	l := &Limiter{rate: limits.Rate, burst: float64(max(limits.Burst, 1))}
+	l.tokens = l.burst
+	if limits.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limits.MaxInFlight)
+	}
+	return l, nil
+}
+
+// WithLimiter makes the Client wait for limiter before sending each request.
+func WithLimiter(limiter *Limiter) ClientOption {
+	return func(c *clientConfig) {
+		c.limiter = limiter
+	}
+}
+
+// WithLimits makes the Client enforce limits on its own requests, with a Limiter created
+// by NewClient. Use WithLimiter instead to share the limits between Clients.
+func WithLimits(limits Limits) ClientOption {
+	return func(c *clientConfig) {
+		c.limits = &limits
+	}
+}
+
+// limitedTransport is an http.RoundTripper waiting for limiter before sending each
+// request, and holding the slot it takes until the response body is closed. It limits
+// the requests Authenticators send on behalf of a Client, such as logins.
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for rate limiter: %w", err)
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: sync.OnceFunc(release)}
+	return resp, nil
+}
+
+// releasingBody is a response body calling release when it is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close implements io.Closer.
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// acquire waits until a request can be sent, or ctx is done. On success, release must
+// be called once the request is over.
+func (l *Limiter) acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := l.waitToken(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// waitToken takes a token from the bucket, waiting for one to be available if it is
+// empty.
+func (l *Limiter) waitToken(ctx context.Context) error {
+	if l.rate == 0 {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if !l.last.IsZero() {
+			l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/t8test"
+)
+
+// newLimitedServer creates a mock server without records, answering every request with
+// 404 Not Found after delay.
+func newLimitedServer(t *testing.T, delay time.Duration) *t8test.Server {
+	t.Helper()
+
+	server := t8test.NewServer(t8test.WithLatency(delay))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// listConcurrently lists the waveforms of a processing mode count times, concurrently,
+// through each of the given clients in turn.
+func listConcurrently(count int, clients ...*datafetcher.Client) {
+	var wg sync.WaitGroup
+	for i := range count {
+		fetcher := datafetcher.NewHttpDataFetcher(clients[i%len(clients)])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = fetcher.ListWaveforms(
+				context.Background(),
+				datafetcher.NewPmodeUrlParams("machine", "point", "pmode"),
+				datafetcher.TimeRange{},
+			)
+		}()
+	}
+	wg.Wait()
+}
+
+// TestClientMaxInFlight tests that a limiter shared by two clients bounds their
+// concurrent requests together.
+func TestClientMaxInFlight(t *testing.T) {
+	server := newLimitedServer(t, 20*time.Millisecond)
+
+	limiter, err := datafetcher.NewLimiter(datafetcher.Limits{MaxInFlight: 2})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+
+	var clients []*datafetcher.Client
+	for range 2 {
+		client, err := datafetcher.NewClient(server.URL, datafetcher.WithLimiter(limiter))
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		clients = append(clients, client)
+	}
+
+	listConcurrently(8, clients...)
+
+	if got := server.MaxConcurrentRequests(); got != 2 {
+		t.Errorf("expected 2 requests in flight at most, got %d", got)
+	}
+}
+
+// TestClientRateLimit tests that requests beyond the burst are spaced by the rate.
+func TestClientRateLimit(t *testing.T) {
+	server := newLimitedServer(t, 0)
+
+	client, err := datafetcher.NewClient(
+		server.URL,
+		datafetcher.WithLimits(datafetcher.Limits{Rate: 20, Burst: 2}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	start := time.Now()
+	listConcurrently(6, client)
+	elapsed := time.Since(start)
+
+	// The burst lets 2 requests through at once, and the other 4 are 50 ms apart.
+	if elapsed < 190*time.Millisecond {
+		t.Errorf("expected 6 requests to take at least 200ms, took %v", elapsed)
+	}
+}
+
+// TestClientLimiterCancellation tests that waiting for the limiter respects the context.
+func TestClientLimiterCancellation(t *testing.T) {
+	server := newLimitedServer(t, 0)
+
+	client, err := datafetcher.NewClient(
+		server.URL,
+		datafetcher.WithLimits(datafetcher.Limits{Rate: 0.1}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	fetcher := datafetcher.NewHttpDataFetcher(client)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := fetcher.GetWaveformContext(ctx, params); !errors.Is(
+		err,
+		context.DeadlineExceeded,
+	) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the wait to stop with the context, took %v", elapsed)
+	}
+}
+
+// TestLimiterInvalidLimits tests that invalid limits are rejected.
+func TestLimiterInvalidLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		limits datafetcher.Limits
+	}{
+		{name: "Negative Rate", limits: datafetcher.Limits{Rate: -1}},
+		{name: "Negative Burst", limits: datafetcher.Limits{Rate: 1, Burst: -1}},
+		{name: "Negative Max In Flight", limits: datafetcher.Limits{MaxInFlight: -1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := datafetcher.NewLimiter(tc.limits); err == nil {
+				t.Errorf("expected error, got none")
+			}
+			_, err := datafetcher.NewClient(
+				"https://t8.example.com",
+				datafetcher.WithLimits(tc.limits),
+			)
+			if err == nil {
+				t.Errorf("expected error from NewClient, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRange is a closed interval of time. A zero From or To leaves the corresponding
+// end of the interval unbounded, so the zero TimeRange contains every instant.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t lies within the range, both ends included.
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// RecordLister discovers which records are stored for a processing mode, so that
+// they can be fetched without knowing their timestamps beforehand.
+type RecordLister interface {
+	// ListWaveforms returns the acquisition times of the waveforms stored for the
+	// given processing mode within timeRange, in ascending order.
+	ListWaveforms(
+		ctx context.Context,
+		urlParams PmodeUrlParams,
+		timeRange TimeRange,
+	) ([]time.Time, error)
+
+	// ListSpectra returns the acquisition times of the spectra stored for the given
+	// processing mode within timeRange, in ascending order.
+	ListSpectra(
+		ctx context.Context,
+		urlParams PmodeUrlParams,
+		timeRange TimeRange,
+	) ([]time.Time, error)
+}
+
+// HttpDataFetcher implements RecordLister.
+var _ RecordLister = HttpDataFetcher{}
+
+// listResponse is the body returned by the T8 listing endpoints: a collection whose
+// items link to the individual records, the last element of each link being the
+// record's Unix timestamp.
+type listResponse struct {
+	Items []struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	} `json:"_items"`
+}
+
+// ListWaveforms retrieves the acquisition times of the waveforms stored in the T8
+// for a processing mode.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - urlParams: A PmodeUrlParams struct identifying the machine, point and processing mode.
+//   - timeRange: The range the returned times must fall in. The zero TimeRange returns every record.
+//
+// Returns:
+//   - []time.Time: The acquisition times, in UTC and ascending order.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) ListWaveforms(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return h.listRecords(ctx, "/waves"+urlParams.path()+"/", timeRange)
+}
+
+// ListSpectra retrieves the acquisition times of the spectra stored in the T8 for a
+// processing mode.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - urlParams: A PmodeUrlParams struct identifying the machine, point and processing mode.
+//   - timeRange: The range the returned times must fall in. The zero TimeRange returns every record.
+//
+// Returns:
+//   - []time.Time: The acquisition times, in UTC and ascending order.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) ListSpectra(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	return h.listRecords(ctx, "/spectra"+urlParams.path()+"/", timeRange)
+}
+
+// listRecords fetches a T8 listing endpoint and returns the timestamps of the listed
+// records that fall within timeRange, sorted in ascending order.
+func (h HttpDataFetcher) listRecords(
+	ctx context.Context,
+	listPath string,
+	timeRange TimeRange,
+) ([]time.Time, error) {
+	if h.client == nil {
+		return nil, errNoClient
+	}
+
+	body, err := h.client.get(ctx, listPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var response listResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("%w: JSON response: %w", ErrDecode, err)
+	}
+
+	times := make([]time.Time, 0, len(response.Items))
+	for _, item := range response.Items {
+		t, err := timestampFromLink(item.Links.Self)
+		if err != nil {
+			return nil, fmt.Errorf("%w: record link %q: %w", ErrDecode, item.Links.Self, err)
+		}
+		if timeRange.Contains(t) {
+			times = append(times, t)
+		}
+	}
+
+	slices.SortFunc(times, time.Time.Compare)
+
+	return times, nil
+}
+
+// timestampFromLink extracts the Unix timestamp that ends a record link such as
+// "https://host/rest/waves/machine/point/pmode/1554907724".
+func timestampFromLink(link string) (time.Time, error) {
+	timestamp, err := strconv.ParseInt(path.Base(strings.TrimRight(link, "/")), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(timestamp, 0).UTC(), nil
+}
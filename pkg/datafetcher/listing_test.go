@@ -0,0 +1,178 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newListingServer creates a mock server answering listing requests for the given
+// kind ("waves" or "spectra") with links to records at the given Unix timestamps.
+func newListingServer(t *testing.T, kind string, timestamps []int64) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expectedPath := fmt.Sprintf("/%s/test_machine/test_point/test_pmode/", kind)
+			if r.URL.Path != expectedPath {
+				http.NotFound(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			body := `{"_items": [`
+			for i, timestamp := range timestamps {
+				if i > 0 {
+					body += ","
+				}
+				body += fmt.Sprintf(
+					`{"_links": {"self": "http://%s%s%d"}}`,
+					r.Host,
+					expectedPath,
+					timestamp,
+				)
+			}
+			body += "]}"
+			if _, err := w.Write([]byte(body)); err != nil {
+				t.Errorf("failed to write response: %v", err)
+			}
+		}),
+	)
+}
+
+// TestListRecords tests listing waveforms and spectra with and without a time range.
+func TestListRecords(t *testing.T) {
+	timestamps := []int64{1554907724, 1554800000, 1555007154}
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	testCases := []struct {
+		name      string
+		timeRange datafetcher.TimeRange
+		expected  []time.Time
+	}{
+		{
+			name:      "Unbounded",
+			timeRange: datafetcher.TimeRange{},
+			expected: []time.Time{
+				time.Unix(1554800000, 0).UTC(),
+				time.Unix(1554907724, 0).UTC(),
+				time.Unix(1555007154, 0).UTC(),
+			},
+		},
+		{
+			name: "Bounded",
+			timeRange: datafetcher.TimeRange{
+				From: time.Unix(1554907724, 0),
+				To:   time.Unix(1555000000, 0),
+			},
+			expected: []time.Time{time.Unix(1554907724, 0).UTC()},
+		},
+		{
+			name:      "Only From",
+			timeRange: datafetcher.TimeRange{From: time.Unix(1554900000, 0)},
+			expected: []time.Time{
+				time.Unix(1554907724, 0).UTC(),
+				time.Unix(1555007154, 0).UTC(),
+			},
+		},
+		{
+			name:      "Empty Range",
+			timeRange: datafetcher.TimeRange{From: time.Unix(1600000000, 0)},
+			expected:  []time.Time{},
+		},
+	}
+
+	for _, kind := range []string{"waves", "spectra"} {
+		mock_server := newListingServer(t, kind, timestamps)
+		fetcher := newTestFetcher(t, mock_server.URL)
+
+		list := fetcher.ListWaveforms
+		if kind == "spectra" {
+			list = fetcher.ListSpectra
+		}
+
+		for _, tc := range testCases {
+			t.Run(kind+"/"+tc.name, func(t *testing.T) {
+				times, err := list(context.Background(), params, tc.timeRange)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+
+				if !reflect.DeepEqual(times, tc.expected) {
+					t.Errorf("expected times %v, got %v", tc.expected, times)
+				}
+			})
+		}
+
+		mock_server.Close()
+	}
+}
+
+// TestListRecordsInvalidLink tests that links not ending in a timestamp are reported as ErrDecode.
+func TestListRecordsInvalidLink(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if _, err := w.Write([]byte(`{"_items": [{"_links": {"self": "http://host/waves/m/p/pm/latest"}}]}`)); err != nil {
+				t.Errorf("failed to write response: %v", err)
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	_, err := fetcher.ListWaveforms(context.Background(), params, datafetcher.TimeRange{})
+	if !errors.Is(err, datafetcher.ErrDecode) {
+		t.Errorf("expected ErrDecode, got %v", err)
+	}
+}
+
+// TestListRecordsNotFound tests that listing an unknown processing mode reports ErrNotFound.
+func TestListRecordsNotFound(t *testing.T) {
+	mock_server := httptest.NewServer(http.NotFoundHandler())
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	_, err := fetcher.ListSpectra(context.Background(), params, datafetcher.TimeRange{})
+	if !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestTimeRangeContains tests the bounds of TimeRange.Contains.
+func TestTimeRangeContains(t *testing.T) {
+	from := time.Date(2019, 4, 10, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2019, 4, 11, 0, 0, 0, 0, time.UTC)
+	timeRange := datafetcher.TimeRange{From: from, To: to}
+
+	testCases := []struct {
+		name     string
+		t        time.Time
+		expected bool
+	}{
+		{name: "Before", t: from.Add(-time.Second), expected: false},
+		{name: "From", t: from, expected: true},
+		{name: "Inside", t: from.Add(time.Hour), expected: true},
+		{name: "To", t: to, expected: true},
+		{name: "After", t: to.Add(time.Second), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := timeRange.Contains(tc.t); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package datafetcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// Direction tells on which side of a requested time the nearest record is looked for.
+type Direction int
+
+const (
+	// Around looks for the nearest record on either side of the requested time.
+	Around Direction = iota
+	// Before looks for the latest record acquired at or before the requested time.
+	Before
+	// After looks for the earliest record acquired at or after the requested time.
+	After
+)
+
+// String returns a lower-case name for the direction, as accepted by ParseDirection.
+func (d Direction) String() string {
+	switch d {
+	case Around:
+		return "around"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	default:
+		return fmt.Sprintf("Direction(%d)", int(d))
+	}
+}
+
+// ParseDirection returns the Direction named s: "around", "before" or "after".
+func ParseDirection(s string) (Direction, error) {
+	for _, d := range []Direction{Around, Before, After} {
+		if s == d.String() {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid direction %q: expected around, before or after", s)
+}
+
+// NearestOptions configures the lookup of the record nearest to a requested time.
+type NearestOptions struct {
+	// Direction is the side of the requested time the record may lie on.
+	Direction Direction
+	// Tolerance is the largest distance allowed between the requested time and the
+	// record. Zero means any distance.
+	Tolerance time.Duration
+}
+
+// searchRange returns the range the records nearest to target are listed in.
+func (o NearestOptions) searchRange(target time.Time) TimeRange {
+	var timeRange TimeRange
+	if o.Tolerance > 0 {
+		timeRange = TimeRange{From: target.Add(-o.Tolerance), To: target.Add(o.Tolerance)}
+	}
+
+	switch o.Direction {
+	case Before:
+		timeRange.To = target
+	case After:
+		timeRange.From = target
+	}
+
+	return timeRange
+}
+
+// FindNearest returns the acquisition time of the record of the given kind nearest to
+// the time of urlParams, looking for it as opts tells. When two records are equally
+// near, the earlier one is returned.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the listing request.
+//   - lister: The RecordLister to list the records with.
+//   - kind: The kind of record to look for.
+//   - urlParams: A PmodeUrlTimeParams struct identifying the processing mode and the
+//     requested time.
+//   - opts: The direction and tolerance of the lookup.
+//
+// Returns:
+//   - time.Time: The acquisition time of the nearest record, in UTC.
+//   - error: An error if the requested time cannot be parsed, matching ErrBadTimestamp, if
+//     the records cannot be listed, or, matching ErrNotFound, if no record is within reach.
+func FindNearest(
+	ctx context.Context,
+	lister RecordLister,
+	kind RecordKind,
+	urlParams PmodeUrlTimeParams,
+	opts NearestOptions,
+) (time.Time, error) {
+	target, err := recordTime(urlParams)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timeRange := opts.searchRange(target)
+
+	var times []time.Time
+	switch kind {
+	case WaveformRecord:
+		times, err = lister.ListWaveforms(ctx, urlParams.PmodeUrlParams, timeRange)
+	case SpectrumRecord:
+		times, err = lister.ListSpectra(ctx, urlParams.PmodeUrlParams, timeRange)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported record kind %s", kind)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error listing %s records: %w", kind, err)
+	}
+
+	// times is sorted and within reach, so the nearest records are the ones on each side
+	// of the position the target would be inserted at.
+	i, found := slices.BinarySearchFunc(times, target, time.Time.Compare)
+	if found {
+		return times[i], nil
+	}
+
+	var candidates []time.Time
+	if opts.Direction != After && i > 0 {
+		candidates = append(candidates, times[i-1])
+	}
+	if opts.Direction != Before && i < len(times) {
+		candidates = append(candidates, times[i])
+	}
+
+	var nearest time.Time
+	for _, t := range candidates {
+		if nearest.IsZero() || distance(t, target) < distance(nearest, target) {
+			nearest = t
+		}
+	}
+
+	if nearest.IsZero() {
+		return time.Time{}, fmt.Errorf(
+			"%w: no %s record %s %s within %s",
+			ErrNotFound,
+			kind,
+			opts.Direction,
+			target.Format(time.RFC3339),
+			tolerance(opts.Tolerance),
+		)
+	}
+
+	return nearest, nil
+}
+
+// GetNearestWaveform retrieves the waveform nearest to the time of urlParams, found
+// with FindNearest. The acquisition time of the waveform actually retrieved is reported
+// in its metadata.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the requests.
+//   - source: The RecordSource to list and fetch the waveforms from.
+//   - urlParams: A PmodeUrlTimeParams struct identifying the processing mode and the
+//     requested time.
+//   - opts: The direction and tolerance of the lookup.
+//
+// Returns:
+//   - waveforms.Waveform: The nearest waveform.
+//   - error: An error if no waveform is found, as reported by FindNearest, or it cannot
+//     be retrieved.
+func GetNearestWaveform(
+	ctx context.Context,
+	source RecordSource,
+	urlParams PmodeUrlTimeParams,
+	opts NearestOptions,
+) (waveforms.Waveform, error) {
+	acquired, err := FindNearest(ctx, source, WaveformRecord, urlParams, opts)
+	if err != nil {
+		return waveforms.Waveform{}, err
+	}
+
+	urlParams.Time = acquired
+	return source.GetWaveformContext(ctx, urlParams)
+}
+
+// GetNearestSpectrum retrieves the spectrum nearest to the time of urlParams, found
+// with FindNearest. The acquisition time of the spectrum actually retrieved is reported
+// in its metadata.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the requests.
+//   - source: The RecordSource to list and fetch the spectra from.
+//   - urlParams: A PmodeUrlTimeParams struct identifying the processing mode and the
+//     requested time.
+//   - opts: The direction and tolerance of the lookup.
+//
+// Returns:
+//   - spectra.Spectrum: The nearest spectrum.
+//   - error: An error if no spectrum is found, as reported by FindNearest, or it cannot
+//     be retrieved.
+func GetNearestSpectrum(
+	ctx context.Context,
+	source RecordSource,
+	urlParams PmodeUrlTimeParams,
+	opts NearestOptions,
+) (spectra.Spectrum, error) {
+	acquired, err := FindNearest(ctx, source, SpectrumRecord, urlParams, opts)
+	if err != nil {
+		return spectra.Spectrum{}, err
+	}
+
+	urlParams.Time = acquired
+	return source.GetSpectrumContext(ctx, urlParams)
+}
+
+// distance returns the absolute time between a and b.
+func distance(a, b time.Time) time.Duration {
+	return a.Sub(b).Abs()
+}
+
+// tolerance describes a lookup tolerance for error messages.
+func tolerance(d time.Duration) string {
+	if d <= 0 {
+		return "any distance"
+	}
+	return d.String()
+}
@@ -0,0 +1,193 @@
+package datafetcher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/t8test"
+)
+
+// nearestBase is the acquisition time of the first record of newNearestServer.
+var nearestBase = time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+
+// newNearestServer creates a mock server holding waveforms and spectra acquired at
+// nearestBase and one and three minutes after it.
+func newNearestServer(t *testing.T) datafetcher.HttpDataFetcher {
+	t.Helper()
+
+	server := t8test.NewServer()
+	t.Cleanup(server.Close)
+
+	for _, offset := range []time.Duration{0, time.Minute, 3 * time.Minute} {
+		waveform := t8test.SineWaveform(2560, 256, t8test.Tone{Frequency: 50, Amplitude: 1})
+		server.AddWaveform("machine", "point", "pmode", nearestBase.Add(offset), waveform)
+		server.AddSpectrum(
+			"machine",
+			"point",
+			"pmode",
+			nearestBase.Add(offset),
+			spectra.SpectrumFromWaveform(waveform, 0, 1000),
+		)
+	}
+
+	client, err := datafetcher.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return datafetcher.NewHttpDataFetcher(client)
+}
+
+// TestFindNearest tests finding the nearest record in every direction.
+func TestFindNearest(t *testing.T) {
+	fetcher := newNearestServer(t)
+
+	testCases := []struct {
+		name     string
+		offset   time.Duration
+		opts     datafetcher.NearestOptions
+		expected time.Duration
+		notFound bool
+	}{
+		{
+			name:     "Exact Match",
+			offset:   time.Minute,
+			opts:     datafetcher.NearestOptions{Direction: datafetcher.Before},
+			expected: time.Minute,
+		},
+		{
+			name:     "Around Picks Nearer",
+			offset:   2*time.Minute + 30*time.Second,
+			expected: 3 * time.Minute,
+		},
+		{
+			name:     "Around Tie Picks Earlier",
+			offset:   2 * time.Minute,
+			expected: time.Minute,
+		},
+		{
+			name:     "Before",
+			offset:   2*time.Minute + 30*time.Second,
+			opts:     datafetcher.NearestOptions{Direction: datafetcher.Before},
+			expected: time.Minute,
+		},
+		{
+			name:     "After",
+			offset:   time.Second,
+			opts:     datafetcher.NearestOptions{Direction: datafetcher.After},
+			expected: time.Minute,
+		},
+		{
+			name:     "Within Tolerance",
+			offset:   -time.Second,
+			opts:     datafetcher.NearestOptions{Tolerance: 2 * time.Second},
+			expected: 0,
+		},
+		{
+			name:     "Beyond Tolerance",
+			offset:   30 * time.Second,
+			opts:     datafetcher.NearestOptions{Tolerance: 10 * time.Second},
+			notFound: true,
+		},
+		{
+			name:     "Nothing Before",
+			offset:   -time.Hour,
+			opts:     datafetcher.NearestOptions{Direction: datafetcher.Before},
+			notFound: true,
+		},
+		{
+			name:     "Nothing After",
+			offset:   time.Hour,
+			opts:     datafetcher.NearestOptions{Direction: datafetcher.After},
+			notFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := datafetcher.NewPmodeUrlTimeParamsAt(
+				"machine",
+				"point",
+				"pmode",
+				nearestBase.Add(tc.offset),
+			)
+
+			for _, kind := range []datafetcher.RecordKind{
+				datafetcher.WaveformRecord,
+				datafetcher.SpectrumRecord,
+			} {
+				result, err := datafetcher.FindNearest(
+					context.Background(),
+					fetcher,
+					kind,
+					params,
+					tc.opts,
+				)
+				if tc.notFound {
+					if !errors.Is(err, datafetcher.ErrNotFound) {
+						t.Errorf("expected ErrNotFound for %s, got %v", kind, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("expected no error for %s, got %v", kind, err)
+				}
+				if expected := nearestBase.Add(tc.expected); !result.Equal(expected) {
+					t.Errorf("expected %s at %v, got %v", kind, expected, result)
+				}
+			}
+		})
+	}
+}
+
+// TestGetNearestRecords tests that the nearest records are fetched and report their
+// acquisition time.
+func TestGetNearestRecords(t *testing.T) {
+	fetcher := newNearestServer(t)
+	params := datafetcher.NewPmodeUrlTimeParamsAt(
+		"machine",
+		"point",
+		"pmode",
+		nearestBase.Add(50*time.Second),
+	)
+	opts := datafetcher.NearestOptions{Tolerance: time.Minute}
+	expected := nearestBase.Add(time.Minute)
+
+	waveform, err := datafetcher.GetNearestWaveform(context.Background(), fetcher, params, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !waveform.Time.Equal(expected) || len(waveform.Samples) != 256 {
+		t.Errorf("expected the waveform at %v, got %+v", expected, waveform.Metadata)
+	}
+
+	spectrum, err := datafetcher.GetNearestSpectrum(context.Background(), fetcher, params, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !spectrum.Time.Equal(expected) {
+		t.Errorf("expected the spectrum at %v, got %+v", expected, spectrum.Metadata)
+	}
+}
+
+// TestParseDirection tests parsing the names of the directions.
+func TestParseDirection(t *testing.T) {
+	for _, d := range []datafetcher.Direction{
+		datafetcher.Around,
+		datafetcher.Before,
+		datafetcher.After,
+	} {
+		parsed, err := datafetcher.ParseDirection(d.String())
+		if err != nil || parsed != d {
+			t.Errorf("expected %s, got %s (%v)", d, parsed, err)
+		}
+	}
+
+	if _, err := datafetcher.ParseDirection("sideways"); err == nil {
+		t.Errorf("expected an error, got none")
+	}
+}
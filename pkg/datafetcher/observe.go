@@ -0,0 +1,248 @@
+package datafetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the requests made by a Client and of the records
+// decoded by its HttpDataFetchers, e.g. to feed counters and histograms labelled by
+// host, endpoint and status. Implementations adapt them to a metrics system such as
+// Prometheus or OpenTelemetry, and must be safe for concurrent use. Metrics are set
+// with WithMetrics.
+type Metrics interface {
+	// ObserveRequest is called once every attempt of a request is done, including the
+	// attempts that are retried.
+	ObserveRequest(stats RequestStats)
+
+	// ObserveDecode is called once every waveform or spectrum response is decoded.
+	ObserveDecode(stats DecodeStats)
+}
+
+// RequestStats describes a single attempt of a request made by a Client.
+type RequestStats struct {
+	// Host is the base URL of the Client.
+	Host string
+	// Endpoint is the first element of the request path relative to Host, such as
+	// "waves", "spectra", "trends" or "machines". It has a small set of values, suitable
+	// for labelling metrics.
+	Endpoint string
+	// URL is the requested URL, without credentials.
+	URL string
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+	// StatusCode is the status code of the response, or 0 if none was received.
+	StatusCode int
+	// Duration is the time from sending the request to reading the whole response.
+	Duration time.Duration
+	// Bytes is the number of bytes of the response body read.
+	Bytes int64
+	// Trace is the breakdown of Duration.
+	Trace TraceTimings
+	// Err is the error the attempt failed with, or nil.
+	Err error
+}
+
+// TraceTimings breaks down the latency of a request, as reported by net/http/httptrace.
+// The phases that did not take place, such as DNS lookup, connection and TLS handshake
+// when a connection is reused, are zero.
+type TraceTimings struct {
+	// DNS is the time spent resolving the host name.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent in the TLS handshake.
+	TLSHandshake time.Duration
+	// Wait is the time from writing the request to receiving the first byte of the
+	// response, i.e. the processing time of the device plus a round trip.
+	Wait time.Duration
+	// ConnReused tells whether the request was sent on a previously used connection.
+	ConnReused bool
+}
+
+// DecodeStats describes the decoding of a waveform or spectrum response.
+type DecodeStats struct {
+	// Host is the base URL of the Client the response was received from.
+	Host string
+	// Kind is the kind of record decoded.
+	Kind RecordKind
+	// Duration is the time spent decoding. The response is decoded as it is received,
+	// so it includes the time spent waiting for the body.
+	Duration time.Duration
+	// Samples is the number of samples or spectral lines decoded.
+	Samples int
+	// Err is the error decoding failed with, or nil.
+	Err error
+}
+
+// WithLogger makes the Client log its requests to logger: every attempt at debug level,
+// with its URL, status, duration, size and latency breakdown, and failures and retries at
+// warning level. By default nothing is logged.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *clientConfig) {
+		c.logger = logger
+	}
+}
+
+// WithMetrics makes the Client report measurements of its requests to metrics.
+func WithMetrics(metrics Metrics) ClientOption {
+	return func(c *clientConfig) {
+		c.metrics = metrics
+	}
+}
+
+// discardLogger is the logger of Clients created without WithLogger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// observed reports whether the requests of the client are logged or measured, so that
+// tracing them is worth it.
+func (c *Client) observed() bool {
+	return c.metrics != nil || c.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// observeRequest logs and records stats.
+func (c *Client) observeRequest(ctx context.Context, stats RequestStats) {
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(stats)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("url", stats.URL),
+		slog.String("endpoint", stats.Endpoint),
+		slog.Int("attempt", stats.Attempt),
+		slog.Int("status", stats.StatusCode),
+		slog.Duration("duration", stats.Duration),
+		slog.Int64("bytes", stats.Bytes),
+		slog.Group(
+			"trace",
+			slog.Duration("dns", stats.Trace.DNS),
+			slog.Duration("connect", stats.Trace.Connect),
+			slog.Duration("tls", stats.Trace.TLSHandshake),
+			slog.Duration("wait", stats.Trace.Wait),
+			slog.Bool("reused", stats.Trace.ConnReused),
+		),
+	}
+	if stats.Err != nil {
+		attrs = append(attrs, slog.Any("error", stats.Err))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "T8 request failed", attrs...)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "T8 request", attrs...)
+}
+
+// observeDecode logs and records stats.
+func (c *Client) observeDecode(ctx context.Context, stats DecodeStats) {
+	stats.Host = c.host
+	if c.metrics != nil {
+		c.metrics.ObserveDecode(stats)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("kind", stats.Kind.String()),
+		slog.Duration("duration", stats.Duration),
+		slog.Int("samples", stats.Samples),
+	}
+	if stats.Err != nil {
+		attrs = append(attrs, slog.Any("error", stats.Err))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "T8 record decoding failed", attrs...)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "T8 record decoded", attrs...)
+}
+
+// requestEndpoint returns the first element of path, a request path relative to the
+// host of a Client.
+func requestEndpoint(path string) string {
+	endpoint, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return endpoint
+}
+
+// redactURL returns rawURL with any password replaced, for logging.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Redacted()
+}
+
+// requestTracer records the timings of a request through an httptrace.ClientTrace. Its
+// hooks may be called from several goroutines, e.g. when dialing several addresses.
+type requestTracer struct {
+	mu       sync.Mutex
+	timings  TraceTimings
+	dnsStart time.Time
+	dialed   time.Time
+	tlsStart time.Time
+	wrote    time.Time
+}
+
+// clientTrace returns the hooks recording the timings of the request.
+func (t *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.dialed.IsZero() {
+				t.dialed = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && t.timings.Connect == 0 {
+				t.timings.Connect = time.Since(t.dialed)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLSHandshake = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.ConnReused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wrote = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.wrote.IsZero() {
+				t.timings.Wait = time.Since(t.wrote)
+			}
+		},
+	}
+}
+
+// result returns the timings recorded so far.
+func (t *requestTracer) result() TraceTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings
+}
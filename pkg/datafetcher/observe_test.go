@@ -0,0 +1,149 @@
+package datafetcher_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/t8test"
+)
+
+// recordingMetrics is a datafetcher.Metrics keeping every measurement.
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []datafetcher.RequestStats
+	decodes  []datafetcher.DecodeStats
+}
+
+func (m *recordingMetrics) ObserveRequest(stats datafetcher.RequestStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, stats)
+}
+
+func (m *recordingMetrics) ObserveDecode(stats datafetcher.DecodeStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decodes = append(m.decodes, stats)
+}
+
+// TestClientObservability tests the measurements and logs of a request retried once.
+func TestClientObservability(t *testing.T) {
+	acquired := time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+	mock_server := t8test.NewServer()
+	defer mock_server.Close()
+	mock_server.AddWaveform(
+		"machine",
+		"point",
+		"pmode",
+		acquired,
+		t8test.SineWaveform(2560, 256, t8test.Tone{Frequency: 50, Amplitude: 1}),
+	)
+	mock_server.InjectFault(
+		t8test.Fault{Status: http.StatusServiceUnavailable, RetryAfter: "0", Count: 1},
+	)
+
+	metrics := &recordingMetrics{}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	policy := datafetcher.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	host := strings.Replace(mock_server.URL, "http://", "http://user:secret@", 1)
+	client, err := datafetcher.NewClient(
+		host,
+		datafetcher.WithRetryPolicy(policy),
+		datafetcher.WithLogger(logger),
+		datafetcher.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = datafetcher.NewHttpDataFetcher(client).GetWaveform(
+		datafetcher.NewPmodeUrlTimeParamsAt("machine", "point", "pmode", acquired),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(metrics.requests) != 2 {
+		t.Fatalf("expected 2 observed requests, got %d", len(metrics.requests))
+	}
+	for i, expectedStatus := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		stats := metrics.requests[i]
+		if stats.Endpoint != "waves" || stats.Attempt != i+1 ||
+			stats.StatusCode != expectedStatus {
+			t.Errorf(
+				"expected attempt %d to waves with status %d, got %+v",
+				i+1,
+				expectedStatus,
+				stats,
+			)
+		}
+		if stats.Duration <= 0 || stats.Trace.Wait <= 0 {
+			t.Errorf("expected durations to be measured, got %+v", stats)
+		}
+		if strings.Contains(stats.URL, "secret") {
+			t.Errorf("expected the URL without password, got %q", stats.URL)
+		}
+	}
+	if metrics.requests[0].Err == nil || metrics.requests[1].Err != nil {
+		t.Errorf("expected only the first attempt to fail, got %+v", metrics.requests)
+	}
+	if metrics.requests[1].Bytes == 0 || !metrics.requests[1].Trace.ConnReused {
+		t.Errorf("expected bytes read on a reused connection, got %+v", metrics.requests[1])
+	}
+
+	if len(metrics.decodes) != 1 || metrics.decodes[0].Kind != datafetcher.WaveformRecord ||
+		metrics.decodes[0].Samples != 256 || metrics.decodes[0].Err != nil {
+		t.Errorf("expected one decoded waveform of 256 samples, got %+v", metrics.decodes)
+	}
+
+	output := logs.String()
+	for _, expected := range []string{
+		"T8 request failed",
+		"retrying T8 request",
+		"T8 request",
+		"T8 record decoded",
+		"status=200",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected logs to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "secret") {
+		t.Errorf("expected logs without password, got:\n%s", output)
+	}
+}
+
+// TestClientLogsFailuresOnly tests that a logger above debug level only gets failures.
+func TestClientLogsFailuresOnly(t *testing.T) {
+	mock_server := t8test.NewServer()
+	defer mock_server.Close()
+	mock_server.InjectFault(t8test.Fault{Status: http.StatusNotFound})
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	client, err := datafetcher.NewClient(mock_server.URL, datafetcher.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	if _, err := datafetcher.NewHttpDataFetcher(client).ListMachines(t.Context()); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	output := logs.String()
+	if strings.Count(output, "\n") != 1 || !strings.Contains(output, "endpoint=machines") ||
+		!strings.Contains(output, "status=404") {
+		t.Errorf("expected a single failure log for machines, got:\n%s", output)
+	}
+}
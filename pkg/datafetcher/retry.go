@@ -0,0 +1,219 @@
+package datafetcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how a Client retries requests that fail with a transient
+// error, such as a dropped connection or a 503 Service Unavailable response. Retries
+// are opt-in: a Client only retries when configured with WithRetryPolicy.
+//
+// The delay before retry n (starting at 1) is InitialBackoff * Multiplier^(n-1),
+// capped at MaxBackoff and randomized by Jitter. When the server answers with a
+// Retry-After header, its value is used as the delay instead, capped at MaxBackoff or,
+// without it, at 24 hours.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values
+	// lower than 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed backoff and the delays requested by Retry-After
+	// headers. Zero means no cap on the backoff.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each retry. Values lower
+	// than 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, by which each backoff is randomly
+	// shortened or lengthened, so that many clients do not retry in lockstep.
+	Jitter float64
+	// RetryableStatusCodes lists the HTTP status codes that trigger a retry. When
+	// nil, 429, 502, 503 and 504 are retried.
+	RetryableStatusCodes []int
+	// OnRetry, if set, is called before waiting for each retry.
+	OnRetry func(RetryAttempt)
+}
+
+// RetryAttempt describes a failed attempt that is about to be retried.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt int
+	// URL is the requested URL.
+	URL string
+	// StatusCode is the HTTP status code of the failed attempt, or 0 if no response
+	// was received.
+	StatusCode int
+	// Err is the error the attempt failed with.
+	Err error
+	// Delay is how long the client waits before the next attempt.
+	Delay time.Duration
+}
+
+// defaultRetryableStatusCodes are the status codes retried when a RetryPolicy does
+// not list its own.
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for T8 devices behind unreliable
+// links: up to 4 attempts, with an exponential backoff starting at 500ms and capped
+// at 10s, randomized by 20%.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetryPolicy makes the Client retry requests that fail with a transient error
+// according to policy. It applies to every request made through the Client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *clientConfig) {
+		c.retryPolicy = policy
+	}
+}
+
+// shouldRetry reports whether a failed attempt, described by its response (nil when
+// no response was received) and error, is worth retrying.
+func (p RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if resp != nil {
+		codes := p.RetryableStatusCodes
+		if codes == nil {
+			codes = defaultRetryableStatusCodes
+		}
+		return slices.Contains(codes, resp.StatusCode)
+	}
+
+	return isTransientError(err)
+}
+
+// defaultMaxRetryAfter caps the delay requested by a Retry-After header when the
+// RetryPolicy has no MaxBackoff.
+const defaultMaxRetryAfter = 24 * time.Hour
+
+// backoff returns the delay before retrying after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := math.Max(p.Multiplier, 1)
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(p.MaxBackoff))
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	delay *= 1 + jitter*(2*rand.Float64()-1)
+
+	// Without a cap the delay grows without bound, up to +Inf, which does not fit in a
+	// Duration.
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// maxRetryAfter returns the longest delay a Retry-After header is honored up to:
+// MaxBackoff or, without it, defaultMaxRetryAfter.
+func (p RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return defaultMaxRetryAfter
+}
+
+// transportError marks an error returned by the transport or while reading a response
+// body, as opposed to one returned while decoding the body, which is never transient.
+type transportError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// isTransientError reports whether err, returned while performing a request, is a
+// network failure that may succeed when retried. Only errors marked as transport errors
+// qualify: a body that fails to decode, even with io.ErrUnexpectedEOF, is not retried.
+func isTransientError(err error) bool {
+	var transport *transportError
+	if !errors.As(err, &transport) {
+		return false
+	}
+	err = transport.err
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter returns the delay requested by a Retry-After header, given either
+// as a number of seconds or as an HTTP date, capped at limit. It returns false if the
+// header is missing or malformed.
+func parseRetryAfter(header string, now time.Time, limit time.Duration) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		// Compared before multiplying, which could overflow.
+		if seconds > int(limit/time.Second) {
+			return limit, true
+		}
+		return min(time.Duration(seconds)*time.Second, limit), true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return min(max(date.Sub(now), 0), limit), true
+}
+
+// sleepContext waits for the given delay, returning early with the context's error
+// if ctx is done first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,366 @@
+package datafetcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newRetryTestFetcher creates an HttpDataFetcher pointing at url that retries with
+// negligible backoff and records every retry attempt in attempts.
+func newRetryTestFetcher(
+	t *testing.T,
+	url string,
+	maxAttempts int,
+	attempts *[]datafetcher.RetryAttempt,
+) datafetcher.HttpDataFetcher {
+	t.Helper()
+
+	policy := datafetcher.RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		OnRetry: func(attempt datafetcher.RetryAttempt) {
+			*attempts = append(*attempts, attempt)
+		},
+	}
+
+	client, err := datafetcher.NewClient(url, datafetcher.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return datafetcher.NewHttpDataFetcher(client)
+}
+
+var retryTestParams = datafetcher.NewPmodeUrlTimeParams(
+	"test_machine",
+	"test_point",
+	"test_pmode",
+	"2019-04-10T14:48:44",
+)
+
+// TestRetryTransientStatus tests that retryable status codes are retried until the request succeeds.
+func TestRetryTransientStatus(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch requests.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				response := datafetcher.WaveformResponse{
+					RawWaveform: "eJxjZPj//389QwMAEP4D/g==",
+					Factor:      1,
+					SampleRate:  2560,
+				}
+				if err := json.NewEncoder(w).Encode(response); err != nil {
+					t.Errorf("failed to encode response: %v", err)
+				}
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 3, &attempts)
+
+	waveform, err := fetcher.GetWaveform(retryTestParams)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(waveform.Samples) != 4 {
+		t.Errorf("expected 4 samples, got %d", len(waveform.Samples))
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 retries, got %d", len(attempts))
+	}
+
+	expectedCodes := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	for i, attempt := range attempts {
+		if attempt.Attempt != i+1 {
+			t.Errorf("expected attempt %d, got %d", i+1, attempt.Attempt)
+		}
+		if attempt.StatusCode != expectedCodes[i] {
+			t.Errorf("expected status code %d, got %d", expectedCodes[i], attempt.StatusCode)
+		}
+		if attempt.Err == nil {
+			t.Errorf("expected attempt %d to carry an error", attempt.Attempt)
+		}
+	}
+}
+
+// TestRetryGivesUpAfterMaxAttempts tests that the last error is returned once all attempts fail.
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 3, &attempts)
+
+	if _, err := fetcher.GetSpectrum(retryTestParams); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", requests.Load())
+	}
+
+	if len(attempts) != 2 {
+		t.Errorf("expected 2 retries, got %d", len(attempts))
+	}
+}
+
+// TestRetryNonRetryableStatus tests that client errors are not retried.
+func TestRetryNonRetryableStatus(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 3, &attempts)
+
+	if _, err := fetcher.GetWaveform(retryTestParams); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+}
+
+// TestRetryDisabledByDefault tests that a Client without a retry policy does not retry.
+func TestRetryDisabledByDefault(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer mock_server.Close()
+
+	fetcher := newTestFetcher(t, mock_server.URL)
+
+	if _, err := fetcher.GetWaveform(retryTestParams); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+}
+
+// TestRetryConnectionReset tests that connections dropped by the server are retried.
+func TestRetryConnectionReset(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				t.Errorf("response writer does not support hijacking")
+				return
+			}
+			conn, _, err := hijacker.Hijack()
+			if err != nil {
+				t.Errorf("failed to hijack connection: %v", err)
+				return
+			}
+			if err := conn.Close(); err != nil {
+				t.Errorf("failed to close connection: %v", err)
+			}
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 2, &attempts)
+
+	if _, err := fetcher.GetWaveform(retryTestParams); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if len(attempts) != 1 {
+		t.Fatalf("expected 1 retry, got %d", len(attempts))
+	}
+
+	if attempts[0].StatusCode != 0 {
+		t.Errorf("expected no status code for a dropped connection, got %d", attempts[0].StatusCode)
+	}
+}
+
+// TestRetryHonorsRetryAfter tests that the Retry-After header sets the retry delay and that
+// waiting for it respects context cancellation.
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 3, &attempts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := fetcher.GetWaveformContext(ctx, retryTestParams)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+
+	if len(attempts) != 1 {
+		t.Fatalf("expected 1 retry, got %d", len(attempts))
+	}
+
+	if attempts[0].Delay != time.Hour {
+		t.Errorf("expected delay of 1h from Retry-After, got %v", attempts[0].Delay)
+	}
+}
+
+// TestDefaultRetryPolicy tests the values of the default retry policy.
+func TestDefaultRetryPolicy(t *testing.T) {
+	policy := datafetcher.DefaultRetryPolicy()
+
+	if policy.MaxAttempts < 2 {
+		t.Errorf("expected default policy to retry, got %d max attempts", policy.MaxAttempts)
+	}
+
+	if policy.InitialBackoff <= 0 || policy.MaxBackoff < policy.InitialBackoff {
+		t.Errorf(
+			"expected a positive, capped backoff, got %v up to %v",
+			policy.InitialBackoff,
+			policy.MaxBackoff,
+		)
+	}
+}
+
+// TestRetryUncappedBackoff tests that a backoff growing beyond the range of a
+// time.Duration is clamped instead of overflowing.
+func TestRetryUncappedBackoff(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	policy := datafetcher.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Multiplier:     math.MaxFloat64,
+		OnRetry: func(attempt datafetcher.RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+	client, err := datafetcher.NewClient(mock_server.URL, datafetcher.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = datafetcher.NewHttpDataFetcher(client).GetWaveformContext(ctx, retryTestParams)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 retries, got %d", len(attempts))
+	}
+	if attempts[1].Delay != math.MaxInt64 {
+		t.Errorf("expected the longest delay, got %v", attempts[1].Delay)
+	}
+}
+
+// TestRetryAfterCapped tests that the delay requested by Retry-After is capped at the
+// maximum backoff, also when it overflows a time.Duration.
+func TestRetryAfterCapped(t *testing.T) {
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "9223372036854775807")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	policy := datafetcher.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Minute,
+		OnRetry: func(attempt datafetcher.RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}
+	client, err := datafetcher.NewClient(mock_server.URL, datafetcher.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _ = datafetcher.NewHttpDataFetcher(client).GetWaveformContext(ctx, retryTestParams)
+
+	if len(attempts) != 1 {
+		t.Fatalf("expected 1 retry, got %d", len(attempts))
+	}
+	if attempts[0].Delay != time.Minute {
+		t.Errorf("expected delay capped at 1m, got %v", attempts[0].Delay)
+	}
+}
+
+// TestRetryTruncatedBody tests that a complete response whose body fails to decode is not
+// retried, even though the decoder reports an unexpected EOF.
+func TestRetryTruncatedBody(t *testing.T) {
+	var requests atomic.Int32
+	mock_server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(`{"data": "eJxjZPj//389QwMAEP4D/g==", "factor"`))
+		}),
+	)
+	defer mock_server.Close()
+
+	var attempts []datafetcher.RetryAttempt
+	fetcher := newRetryTestFetcher(t, mock_server.URL, 3, &attempts)
+
+	_, err := fetcher.GetWaveform(retryTestParams)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected an unexpected EOF decoding the body, got %v", err)
+	}
+
+	if len(attempts) != 0 || requests.Load() != 1 {
+		t.Errorf("expected no retry, got %d retries of %d requests", len(attempts), requests.Load())
+	}
+}
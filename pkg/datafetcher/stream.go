@@ -0,0 +1,428 @@
+package datafetcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/codec"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+	"gonum.org/v1/gonum/floats"
+)
+
+// dataField is the member of T8 record responses holding the encoded samples.
+const dataField = "data"
+
+// errMissingData is returned by decodeRecord when the object has no "data" member.
+var errMissingData = errors.New(`missing "data" member`)
+
+// DecodeWaveform decodes a waveform from r, which yields a JSON document shaped like
+// WaveformResponse, as served by the T8 waves endpoint. The encoded samples are decoded
+// as they are read, so the response is never held in memory as a whole.
+//
+// Parameters:
+//   - r: The reader yielding the JSON document.
+//
+// Returns:
+//   - waveforms.Waveform: A struct containing the decoded waveform data, including samples, sample rate,
+//     and the metadata found in the document: factor, units, speed and any unknown member.
+//   - error: An error matching ErrDecode if the document cannot be decoded, or the error reading from r.
+func DecodeWaveform(r io.Reader) (waveforms.Waveform, error) {
+	var response WaveformResponse
+	var samples []float64
+
+	members, err := decodeRecord(r, &response, func(data io.Reader) error {
+		var err error
+		samples, err = codec.ZintReaderToFloat(data)
+		if err != nil {
+			return fmt.Errorf("%w: waveform data: %w", ErrDecode, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return waveforms.Waveform{}, decodeError(err)
+	}
+
+	floats.Scale(response.Factor, samples)
+
+	return waveforms.Waveform{
+		Metadata: metadata.Metadata{
+			Factor: response.Factor,
+			Units:  response.Units,
+			Speed:  response.Speed,
+			Extras: extraMembers(members, waveformMembers),
+		},
+		Samples:    samples,
+		SampleRate: response.SampleRate,
+	}, nil
+}
+
+// DecodeSpectrum decodes a spectrum from r, which yields a JSON document shaped like
+// SpectrumResponse, as served by the T8 spectra endpoint. The encoded magnitudes are
+// decoded as they are read, so the response is never held in memory as a whole.
+//
+// Parameters:
+//   - r: The reader yielding the JSON document.
+//
+// Returns:
+//   - spectra.Spectrum: A struct containing the decoded spectrum data, including frequencies, magnitudes
+//     the frequency range, and the metadata found in the document: factor, units, speed and any
+//     unknown member.
+//   - error: An error matching ErrDecode if the document cannot be decoded, or the error reading from r.
+func DecodeSpectrum(r io.Reader) (spectra.Spectrum, error) {
+	var response SpectrumResponse
+	var magnitudes []float64
+
+	members, err := decodeRecord(r, &response, func(data io.Reader) error {
+		var err error
+		magnitudes, err = codec.ZintReaderToFloat(data)
+		if err != nil {
+			return fmt.Errorf("%w: spectrum data: %w", ErrDecode, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return spectra.Spectrum{}, decodeError(err)
+	}
+
+	floats.Scale(response.Factor, magnitudes)
+
+	spectrum := spectra.NewSpectrum(magnitudes, response.Fmin, response.Fmax)
+	spectrum.Metadata = metadata.Metadata{
+		Factor: response.Factor,
+		Units:  response.Units,
+		Speed:  response.Speed,
+		Extras: extraMembers(members, spectrumMembers),
+	}
+
+	return spectrum, nil
+}
+
+// extraMembers returns the members that are not listed in known, or nil if there are none.
+func extraMembers(members map[string]json.RawMessage, known []string) map[string]json.RawMessage {
+	var extras map[string]json.RawMessage
+	for key, value := range members {
+		if slices.Contains(known, key) {
+			continue
+		}
+		if extras == nil {
+			extras = map[string]json.RawMessage{}
+		}
+		extras[key] = value
+	}
+	return extras
+}
+
+// decodeError wraps an error returned by decodeRecord with ErrDecode, unless it already
+// matches it.
+func decodeError(err error) error {
+	if errors.Is(err, ErrDecode) {
+		return err
+	}
+	return fmt.Errorf("%w: JSON response: %w", ErrDecode, err)
+}
+
+// decodeRecord reads a T8 record response, a JSON object, from r. The value of its
+// "data" member, a JSON string that may be several megabytes long, is streamed to
+// decodeData as it is read, without ever being held in memory as a whole. The other
+// members are decoded into v as with json.Unmarshal, and returned as raw JSON.
+func decodeRecord(
+	r io.Reader,
+	v any,
+	decodeData func(io.Reader) error,
+) (map[string]json.RawMessage, error) {
+	br := bufio.NewReader(r)
+	members := map[string]json.RawMessage{}
+	foundData := false
+
+	if err := expectByte(br, '{'); err != nil {
+		return nil, err
+	}
+
+	next, err := peekNonSpace(br)
+	if err != nil {
+		return nil, err
+	}
+
+	for next != '}' {
+		key, err := readKey(br)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == dataField {
+			if foundData {
+				return nil, errors.New(`duplicate "data" member`)
+			}
+			foundData = true
+			if err := readDataString(br, decodeData); err != nil {
+				return nil, err
+			}
+		} else {
+			value, err := readRawValue(br)
+			if err != nil {
+				return nil, err
+			}
+			members[key] = value
+		}
+
+		next, err = peekNonSpace(br)
+		if err != nil {
+			return nil, err
+		}
+		if next == ',' {
+			if _, err := br.ReadByte(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if next != '}' {
+			return nil, fmt.Errorf("invalid character %q after object member", next)
+		}
+	}
+
+	if _, err := br.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	if _, err := peekNonSpace(br); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after top-level object")
+	}
+
+	if !foundData {
+		return nil, errMissingData
+	}
+
+	encodedMembers, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(encodedMembers, v); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// readKey reads an object member name and the colon that follows it.
+func readKey(br *bufio.Reader) (string, error) {
+	raw, err := readRawValue(br)
+	if err != nil {
+		return "", err
+	}
+
+	var key string
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return "", fmt.Errorf("invalid object member name: %w", err)
+	}
+
+	if err := expectByte(br, ':'); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// readDataString streams the JSON string value read from br to decodeData, then
+// discards whatever decodeData left unread, up to the closing quote.
+func readDataString(br *bufio.Reader, decodeData func(io.Reader) error) error {
+	if err := expectByte(br, '"'); err != nil {
+		return fmt.Errorf(`"data" member must be a string: %w`, err)
+	}
+
+	sr := &jsonStringReader{r: br}
+	if err := decodeData(sr); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(io.Discard, sr)
+	return err
+}
+
+// readRawValue reads a single JSON value from br, without decoding it.
+func readRawValue(br *bufio.Reader) (json.RawMessage, error) {
+	if _, err := peekNonSpace(br); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	var value bytes.Buffer
+	depth := 0
+	inString := false
+	escaped := false
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && depth == 0 && !inString {
+				return value.Bytes(), nil
+			}
+			return nil, unexpectedEOF(err)
+		}
+
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			if depth == 0 {
+				return endRawValue(br, value.Bytes(), c)
+			}
+			depth--
+		case (c == ',' || isSpace(c)) && depth == 0:
+			return endRawValue(br, value.Bytes(), c)
+		}
+
+		value.WriteByte(c)
+		if depth == 0 && !inString && (c == '"' || c == '}' || c == ']') {
+			return value.Bytes(), nil
+		}
+	}
+}
+
+// endRawValue ends a value read by readRawValue at the delimiter c, which is put back
+// into br. It fails if the value is empty.
+func endRawValue(br *bufio.Reader, value []byte, c byte) (json.RawMessage, error) {
+	if len(value) == 0 {
+		return nil, fmt.Errorf("invalid character %q, expected a value", c)
+	}
+	return value, br.UnreadByte()
+}
+
+// jsonStringReader yields the unescaped contents of a JSON string being read from r,
+// whose opening quote has already been consumed. It returns io.EOF once the closing
+// quote is reached.
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+// Read implements io.Reader.
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && !s.done {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return n, unexpectedEOF(err)
+		}
+
+		switch c {
+		case '"':
+			s.done = true
+		case '\\':
+			c, err = s.readEscape()
+			if err != nil {
+				return n, err
+			}
+			p[n] = c
+			n++
+		default:
+			if c < 0x20 {
+				return n, fmt.Errorf("invalid control character %q in string", c)
+			}
+			p[n] = c
+			n++
+		}
+	}
+
+	if s.done && n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// readEscape reads the escape sequence following a backslash and returns the byte it
+// stands for. Only escapes of ASCII characters are supported, which covers every
+// character of base64-encoded data.
+func (s *jsonStringReader) readEscape() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+
+	switch c {
+	case '"', '\\', '/':
+		return c, nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		hex := make([]byte, 4)
+		if _, err := io.ReadFull(s.r, hex); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		code, err := strconv.ParseUint(string(hex), 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid unicode escape %q: %w", hex, err)
+		}
+		if code >= 0x80 {
+			return 0, fmt.Errorf("unsupported non-ASCII escape \\u%s in data string", hex)
+		}
+		return byte(code), nil
+	default:
+		return 0, fmt.Errorf("invalid escape character %q", c)
+	}
+}
+
+// expectByte skips whitespace and consumes the next byte, which must be want.
+func expectByte(br *bufio.Reader, want byte) error {
+	c, err := peekNonSpace(br)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if c != want {
+		return fmt.Errorf("invalid character %q, expected %q", c, want)
+	}
+	_, err = br.ReadByte()
+	return err
+}
+
+// peekNonSpace skips whitespace and returns the next byte without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isSpace(c) {
+			return c, br.UnreadByte()
+		}
+	}
+}
+
+// isSpace reports whether c is JSON whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// unexpectedEOF turns io.EOF into io.ErrUnexpectedEOF, for errors found in the middle
+// of a JSON value.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
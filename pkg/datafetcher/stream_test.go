@@ -0,0 +1,239 @@
+package datafetcher_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// TestDecodeWaveform tests decoding waveform documents with different layouts.
+func TestDecodeWaveform(t *testing.T) {
+	expected := waveforms.Waveform{
+		Metadata:   metadata.Metadata{Factor: 2},
+		Samples:    []float64{2, -2, 65534, -65536},
+		SampleRate: 2560,
+	}
+
+	speed := 1480.0
+	expectedWithExtras := expected
+	expectedWithExtras.Metadata = metadata.Metadata{
+		Factor: 2,
+		Units:  `g "peak"`,
+		Speed:  &speed,
+		Extras: map[string]json.RawMessage{
+			"t": json.RawMessage(`1554907724`),
+			"_links": json.RawMessage(
+				`{"self": "http://t8/waves/m/p/pm/1554907724", "list": [1, {}]}`,
+			),
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		document string
+		expected waveforms.Waveform
+	}{
+		{
+			name:     "Data First",
+			document: `{"data": "eJxjZPj//389QwMAEP4D/g==", "factor": 2, "sample_rate": 2560}`,
+			expected: expected,
+		},
+		{
+			name:     "Data Last",
+			document: `{"factor": 2, "sample_rate": 2560, "data": "eJxjZPj//389QwMAEP4D/g=="}`,
+			expected: expected,
+		},
+		{
+			name:     "Escaped Slashes",
+			document: `{"data": "eJxjZPj\/\/389QwMAEP4D\/g==", "factor": 2, "sample_rate": 2560}`,
+			expected: expected,
+		},
+		{
+			name: "Extra Members",
+			document: `{
+				"t": 1554907724,
+				"data": "eJxjZPj//389QwMAEP4D/g==",
+				"factor": 2.0,
+				"units": "g \"peak\"",
+				"speed": 1480,
+				"_links": {"self": "http://t8/waves/m/p/pm/1554907724", "list": [1, {}]},
+				"sample_rate": 2560
+			}`,
+			expected: expectedWithExtras,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			waveform, err := datafetcher.DecodeWaveform(
+				iotest.OneByteReader(strings.NewReader(tc.document)),
+			)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !reflect.DeepEqual(waveform, tc.expected) {
+				t.Errorf("expected waveform %+v, got %+v", tc.expected, waveform)
+			}
+		})
+	}
+}
+
+// TestDecodeSpectrum tests decoding a spectrum document.
+func TestDecodeSpectrum(t *testing.T) {
+	document := `{"data": "eJxjZPj//389QwMAEP4D/g==", "factor": 1, "min_freq": 0, "max_freq": 300}`
+
+	spectrum, err := datafetcher.DecodeSpectrum(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := spectra.NewSpectrum([]float64{1, -1, 32767, -32768}, 0, 300)
+	expected.Factor = 1
+	if !reflect.DeepEqual(spectrum, expected) {
+		t.Errorf("expected spectrum %+v, got %+v", expected, spectrum)
+	}
+}
+
+// TestDecodeWaveformErrors tests that malformed documents are reported as ErrDecode.
+func TestDecodeWaveformErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+	}{
+		{name: "Empty", document: ``},
+		{name: "Not An Object", document: `[]`},
+		{name: "Missing Data", document: `{"factor": 1, "sample_rate": 2560}`},
+		{name: "Data Not A String", document: `{"data": 12, "factor": 1}`},
+		{name: "Invalid Data", document: `{"data": "invalid_data", "factor": 1}`},
+		{
+			name:     "Duplicate Data",
+			document: `{"data": "eJxjZPj//389QwMAEP4D/g==", "data": "eJxjZPj//389QwMAEP4D/g=="}`,
+		},
+		{name: "Missing Value", document: `{"factor": , "data": "eJxjZPj//389QwMAEP4D/g=="}`},
+		{name: "Wrong Type", document: `{"factor": "2", "data": "eJxjZPj//389QwMAEP4D/g=="}`},
+		{name: "Truncated", document: `{"data": "eJxjZPj//389QwMAEP4D/g==", "factor": 1`},
+		{name: "Trailing Data", document: `{"data": "eJxjZPj//389QwMAEP4D/g=="} {}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			waveform, err := datafetcher.DecodeWaveform(strings.NewReader(tc.document))
+			if !errors.Is(err, datafetcher.ErrDecode) {
+				t.Errorf("expected ErrDecode, got %v", err)
+			}
+
+			if !reflect.DeepEqual(waveform, waveforms.Waveform{}) {
+				t.Errorf("expected empty waveform, got %+v", waveform)
+			}
+		})
+	}
+}
+
+// largeWaveformDocument builds a waveform document holding the given number of samples.
+func largeWaveformDocument(tb testing.TB, samples int) []byte {
+	tb.Helper()
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	for i := range samples {
+		if err := binary.Write(zw, binary.LittleEndian, int16(i)); err != nil {
+			tb.Fatalf("failed to write sample: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tb.Fatalf("failed to compress samples: %v", err)
+	}
+
+	document, err := json.Marshal(datafetcher.WaveformResponse{
+		RawWaveform: base64.StdEncoding.EncodeToString(compressed.Bytes()),
+		Factor:      0.001,
+		SampleRate:  25600,
+	})
+	if err != nil {
+		tb.Fatalf("failed to encode document: %v", err)
+	}
+
+	return document
+}
+
+// bufferedDecodeWaveform decodes the samples of a waveform document the way they were
+// decoded before streaming was introduced, holding the body, the encoded string, the
+// compressed data and the decompressed data in memory at once.
+func bufferedDecodeWaveform(r io.Reader) ([]float64, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var response datafetcher.WaveformResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(response.RawWaveform)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []float64
+	for i := 0; i+1 < len(decompressed); i += 2 {
+		samples = append(
+			samples,
+			float64(int16(binary.LittleEndian.Uint16(decompressed[i:]))),
+		)
+	}
+
+	return samples, nil
+}
+
+// BenchmarkDecodeWaveform compares decoding a large waveform document by buffering the
+// whole response, as done before streaming was introduced, with DecodeWaveform.
+func BenchmarkDecodeWaveform(b *testing.B) {
+	for _, samples := range []int{1 << 16, 1 << 20} {
+		document := largeWaveformDocument(b, samples)
+
+		b.Run(fmt.Sprintf("Buffered/%d", samples), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(document)))
+			for range b.N {
+				if _, err := bufferedDecodeWaveform(bytes.NewReader(document)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Streaming/%d", samples), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(document)))
+			for range b.N {
+				if _, err := datafetcher.DecodeWaveform(bytes.NewReader(document)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
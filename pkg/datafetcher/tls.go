@@ -0,0 +1,227 @@
+package datafetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// tlsOptions collects the TLS settings of a Client other than a whole *tls.Config.
+type tlsOptions struct {
+	caPEM           [][]byte
+	caFiles         []string
+	clientCerts     []tls.Certificate
+	clientCertFiles [][2]string
+	pins            []string
+	insecure        bool
+}
+
+// isSet reports whether any TLS setting was given.
+func (o tlsOptions) isSet() bool {
+	return len(o.caPEM) > 0 || len(o.caFiles) > 0 || len(o.clientCerts) > 0 ||
+		len(o.clientCertFiles) > 0 || len(o.pins) > 0 || o.insecure
+}
+
+// WithCACertificates trusts the PEM-encoded CA certificates in pem, in addition to the
+// system roots, e.g. to reach devices with certificates signed by a plant CA or with
+// self-signed certificates. It cannot be combined with WithPinnedCertificate.
+func WithCACertificates(pem []byte) ClientOption {
+	return func(c *clientConfig) {
+		c.tls.caPEM = append(c.tls.caPEM, pem)
+	}
+}
+
+// WithCAFile trusts the PEM-encoded CA certificates in the file at path, like
+// WithCACertificates. The file is read by NewClient. It cannot be combined with
+// WithPinnedCertificate.
+func WithCAFile(path string) ClientOption {
+	return func(c *clientConfig) {
+		c.tls.caFiles = append(c.tls.caFiles, path)
+	}
+}
+
+// WithClientCertificate presents cert to devices requesting a client certificate, for
+// mutual TLS.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *clientConfig) {
+		c.tls.clientCerts = append(c.tls.clientCerts, cert)
+	}
+}
+
+// WithClientCertificateFiles presents the PEM-encoded certificate and private key in the
+// given files to devices requesting a client certificate, like WithClientCertificate.
+// The files are read by NewClient.
+func WithClientCertificateFiles(certFile, keyFile string) ClientOption {
+	return func(c *clientConfig) {
+		c.tls.clientCertFiles = append(c.tls.clientCertFiles, [2]string{certFile, keyFile})
+	}
+}
+
+// WithPinnedCertificate only accepts connections to devices presenting a certificate
+// whose SHA-256 fingerprint, as returned by CertificateFingerprint, is fingerprint.
+// Colons and case are ignored. The option can be given several times to accept any of
+// the fingerprints, e.g. while a certificate is being replaced.
+//
+// A pinned certificate is accepted regardless of who signed it and of the names it is
+// valid for, so pinning is the way to trust a single self-signed device. As no CA is
+// consulted, NewClient rejects pins combined with WithCACertificates or WithCAFile, and
+// the RootCAs of the configuration set with WithTLSConfig are ignored. Its
+// VerifyConnection, if any, is still called once the pinned certificate is accepted.
+func WithPinnedCertificate(fingerprint string) ClientOption {
+	return func(c *clientConfig) {
+		c.tls.pins = append(c.tls.pins, fingerprint)
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the certificates presented by the
+// device, which makes the connection vulnerable to interception. It is meant for
+// testing only: prefer WithCACertificates or WithPinnedCertificate. NewClient logs a
+// warning when this option is used, through the logger set with WithLogger or, if there
+// is none, slog.Default.
+func WithInsecureSkipVerify() ClientOption {
+	return func(c *clientConfig) {
+		c.tls.insecure = true
+	}
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of cert, as accepted by
+// WithPinnedCertificate: the hexadecimal digest of its DER encoding, in upper case, with
+// the bytes separated by colons.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// buildTLSConfig returns a copy of base, or a new configuration if base is nil, with
+// opts applied. Disabling verification is warned about through logger.
+func buildTLSConfig(
+	base *tls.Config,
+	opts tlsOptions,
+	logger *slog.Logger,
+) (*tls.Config, error) {
+	if len(opts.pins) > 0 && (len(opts.caPEM) > 0 || len(opts.caFiles) > 0) {
+		return nil, errors.New(
+			"pinned certificates cannot be combined with CA certificates, " +
+				"which are not consulted when pinning",
+		)
+	}
+
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if len(opts.caPEM) > 0 || len(opts.caFiles) > 0 {
+		pool, err := caPool(config.RootCAs, opts)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	config.Certificates = append(config.Certificates, opts.clientCerts...)
+	for _, files := range opts.clientCertFiles {
+		cert, err := tls.LoadX509KeyPair(files[0], files[1])
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+
+	if len(opts.pins) > 0 {
+		pins, err := parseFingerprints(opts.pins)
+		if err != nil {
+			return nil, err
+		}
+		// The chain is not verified against any CA, so the fingerprint check below
+		// replaces the default verification entirely.
+		config.InsecureSkipVerify = true
+		baseVerify := config.VerifyConnection
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("device presented no certificate")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			for _, pin := range pins {
+				if bytes.Equal(sum[:], pin) {
+					if baseVerify != nil {
+						return baseVerify(state)
+					}
+					return nil
+				}
+			}
+			return fmt.Errorf(
+				"certificate fingerprint %s is not pinned",
+				CertificateFingerprint(state.PeerCertificates[0]),
+			)
+		}
+	}
+
+	if opts.insecure {
+		logger.Warn(
+			"TLS certificate verification is disabled; " +
+				"connections to the T8 device can be intercepted",
+		)
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// caPool returns a pool holding the certificates of base, or of the system if base is
+// nil, and the CA certificates of opts.
+func caPool(base *x509.CertPool, opts tlsOptions) (*x509.CertPool, error) {
+	var pool *x509.CertPool
+	if base != nil {
+		pool = base.Clone()
+	} else if systemPool, err := x509.SystemCertPool(); err == nil {
+		pool = systemPool
+	} else {
+		pool = x509.NewCertPool()
+	}
+
+	bundles := slices.Clone(opts.caPEM)
+	for _, path := range opts.caFiles {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		bundles = append(bundles, pem)
+	}
+
+	for _, pem := range bundles {
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA bundle holds no PEM-encoded certificate")
+		}
+	}
+
+	return pool, nil
+}
+
+// parseFingerprints decodes SHA-256 fingerprints written in hexadecimal, with or
+// without colons.
+func parseFingerprints(fingerprints []string) ([][]byte, error) {
+	pins := make([][]byte, 0, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		pin, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", fingerprint)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
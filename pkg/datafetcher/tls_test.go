@@ -0,0 +1,281 @@
+package datafetcher_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newTLSServer creates a mock server with a self-signed certificate, answering every
+// request with 404 Not Found.
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}),
+	)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// certificatePEM returns the PEM encoding of cert.
+func certificatePEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// getWaveformOver requests a waveform from url through a Client created with opts, and
+// returns the error.
+func getWaveformOver(t *testing.T, url string, opts ...datafetcher.ClientOption) error {
+	t.Helper()
+
+	client, err := datafetcher.NewClient(url, opts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = datafetcher.NewHttpDataFetcher(client).GetWaveform(
+		datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44"),
+	)
+	return err
+}
+
+// TestClientTLSVerification tests the options to trust a self-signed device.
+func TestClientTLSVerification(t *testing.T) {
+	server := newTLSServer(t)
+	fingerprint := datafetcher.CertificateFingerprint(server.Certificate())
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, certificatePEM(server.Certificate()), 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []datafetcher.ClientOption
+		mustFail bool
+	}{
+		{
+			name:     "Default Verification",
+			mustFail: true,
+		},
+		{
+			name: "CA Certificates",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithCACertificates(certificatePEM(server.Certificate())),
+			},
+		},
+		{
+			name: "CA File",
+			opts: []datafetcher.ClientOption{datafetcher.WithCAFile(caFile)},
+		},
+		{
+			name: "Pinned Certificate",
+			opts: []datafetcher.ClientOption{datafetcher.WithPinnedCertificate(fingerprint)},
+		},
+		{
+			name: "Pinned Certificate Without Colons",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithPinnedCertificate(
+					strings.ToLower(strings.ReplaceAll(fingerprint, ":", "")),
+				),
+			},
+		},
+		{
+			name: "Other Pinned Certificate",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithPinnedCertificate(strings.Repeat("00", 32)),
+			},
+			mustFail: true,
+		},
+		{
+			name: "Pin Chained To Base Verification",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithTLSConfig(&tls.Config{
+					VerifyConnection: func(tls.ConnectionState) error {
+						return errors.New("rejected by base configuration")
+					},
+				}),
+				datafetcher.WithPinnedCertificate(fingerprint),
+			},
+			mustFail: true,
+		},
+		{
+			name: "Insecure Skip Verify",
+			opts: []datafetcher.ClientOption{datafetcher.WithInsecureSkipVerify()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := getWaveformOver(t, server.URL, tc.opts...)
+			if tc.mustFail {
+				if err == nil || errors.Is(err, datafetcher.ErrNotFound) {
+					t.Errorf("expected a TLS error, got %v", err)
+				}
+			} else if !errors.Is(err, datafetcher.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+// TestClientTLSInvalidOptions tests that unusable TLS options are rejected by NewClient.
+func TestClientTLSInvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	emptyFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFile, []byte("no certificates here"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	fingerprint := strings.Repeat("00", 32)
+
+	testCases := []struct {
+		name string
+		opts []datafetcher.ClientOption
+	}{
+		{
+			name: "Missing CA File",
+			opts: []datafetcher.ClientOption{datafetcher.WithCAFile(filepath.Join(dir, "missing"))},
+		},
+		{
+			name: "Empty CA Bundle",
+			opts: []datafetcher.ClientOption{datafetcher.WithCAFile(emptyFile)},
+		},
+		{
+			name: "Short Fingerprint",
+			opts: []datafetcher.ClientOption{datafetcher.WithPinnedCertificate("AB:CD")},
+		},
+		{
+			name: "Non Hex Fingerprint",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithPinnedCertificate("not a fingerprint"),
+			},
+		},
+		{
+			name: "Missing Client Certificate",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithClientCertificateFiles(emptyFile, emptyFile),
+			},
+		},
+		{
+			name: "Pin With CA Certificates",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithCACertificates([]byte("ignored")),
+				datafetcher.WithPinnedCertificate(fingerprint),
+			},
+		},
+		{
+			name: "Pin With CA File",
+			opts: []datafetcher.ClientOption{
+				datafetcher.WithCAFile(emptyFile),
+				datafetcher.WithPinnedCertificate(fingerprint),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := datafetcher.NewClient("https://t8.example.com", tc.opts...); err == nil {
+				t.Errorf("expected error, got none")
+			}
+		})
+	}
+}
+
+// newClientCertificate creates a self-signed client certificate, and writes it and its
+// key to PEM files in dir.
+func newClientCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "t8-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, certificatePEM(cert), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return cert, certFile, keyFile
+}
+
+// TestClientMutualTLS tests presenting a client certificate.
+func TestClientMutualTLS(t *testing.T) {
+	cert, certFile, keyFile := newClientCertificate(t, t.TempDir())
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}),
+	)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	pin := datafetcher.WithPinnedCertificate(
+		datafetcher.CertificateFingerprint(server.Certificate()),
+	)
+
+	if err := getWaveformOver(t, server.URL, pin); err == nil ||
+		errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected a TLS error without client certificate, got %v", err)
+	}
+
+	err := getWaveformOver(
+		t,
+		server.URL,
+		pin,
+		datafetcher.WithClientCertificateFiles(certFile, keyFile),
+	)
+	if !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package datafetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/codec"
+	"gonum.org/v1/gonum/floats"
+)
+
+// Trend is a time series of the scalar trend parameters (overall RMS, peak, crest
+// factor, bias...) computed by a T8 for a processing mode. Every parameter holds one
+// value per entry of Times.
+type Trend struct {
+	Times      []time.Time
+	Parameters []TrendParameter
+}
+
+// TrendParameter holds the values of a single trend parameter over time.
+type TrendParameter struct {
+	Name   string
+	Units  string
+	Values []float64
+}
+
+// Parameter returns the trend parameter with the given name, and whether it exists.
+func (t Trend) Parameter(name string) (TrendParameter, bool) {
+	for _, parameter := range t.Parameters {
+		if parameter.Name == name {
+			return parameter, true
+		}
+	}
+	return TrendParameter{}, false
+}
+
+type TrendResponse struct {
+	Timestamps []int64                  `json:"timestamps"`
+	Parameters []TrendParameterResponse `json:"params"`
+}
+
+type TrendParameterResponse struct {
+	Name    string  `json:"name"`
+	Units   string  `json:"units"`
+	RawData string  `json:"data"`
+	Factor  float64 `json:"factor"`
+}
+
+// GetTrend retrieves the history of the trend parameters of a processing mode.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the request.
+//   - urlParams: A PmodeUrlParams struct identifying the machine, point and processing mode.
+//   - timeRange: The range the returned values must fall in. The zero TimeRange returns the
+//     whole history.
+//
+// Returns:
+//   - Trend: The decoded time series, in the order reported by the device.
+//   - error: An error if the request fails or the response cannot be decoded.
+func (h HttpDataFetcher) GetTrend(
+	ctx context.Context,
+	urlParams PmodeUrlParams,
+	timeRange TimeRange,
+) (Trend, error) {
+	if h.client == nil {
+		return Trend{}, errNoClient
+	}
+
+	query := url.Values{}
+	if !timeRange.From.IsZero() {
+		query.Set("from", strconv.FormatInt(timeRange.From.Unix(), 10))
+	}
+	if !timeRange.To.IsZero() {
+		query.Set("to", strconv.FormatInt(timeRange.To.Unix(), 10))
+	}
+
+	trendPath := "/trends" + urlParams.path() + "/"
+	if len(query) > 0 {
+		trendPath += "?" + query.Encode()
+	}
+
+	body, err := h.client.get(ctx, trendPath)
+	if err != nil {
+		return Trend{}, err
+	}
+
+	var trendResponse TrendResponse
+	if err := json.Unmarshal(body, &trendResponse); err != nil {
+		return Trend{}, fmt.Errorf("%w: JSON response: %w", ErrDecode, err)
+	}
+
+	return trendFromResponse(trendResponse, timeRange)
+}
+
+// trendFromResponse decodes the parameters of a TrendResponse, keeping only the
+// values whose timestamp lies within timeRange.
+func trendFromResponse(trendResponse TrendResponse, timeRange TimeRange) (Trend, error) {
+	var keep []int
+	trend := Trend{Times: []time.Time{}, Parameters: []TrendParameter{}}
+	for i, timestamp := range trendResponse.Timestamps {
+		t := time.Unix(timestamp, 0).UTC()
+		if timeRange.Contains(t) {
+			keep = append(keep, i)
+			trend.Times = append(trend.Times, t)
+		}
+	}
+
+	for _, parameterResponse := range trendResponse.Parameters {
+		values, err := codec.ZintToFloat(parameterResponse.RawData)
+		if err != nil {
+			return Trend{}, fmt.Errorf(
+				"%w: trend parameter %q: %w",
+				ErrDecode,
+				parameterResponse.Name,
+				err,
+			)
+		}
+
+		if len(values) != len(trendResponse.Timestamps) {
+			return Trend{}, fmt.Errorf(
+				"%w: trend parameter %q has %d values for %d timestamps",
+				ErrDecode,
+				parameterResponse.Name,
+				len(values),
+				len(trendResponse.Timestamps),
+			)
+		}
+
+		floats.Scale(parameterResponse.Factor, values)
+
+		kept := make([]float64, len(keep))
+		for i, index := range keep {
+			kept[i] = values[index]
+		}
+
+		trend.Parameters = append(trend.Parameters, TrendParameter{
+			Name:   parameterResponse.Name,
+			Units:  parameterResponse.Units,
+			Values: kept,
+		})
+	}
+
+	return trend, nil
+}
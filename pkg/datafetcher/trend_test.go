@@ -0,0 +1,178 @@
+package datafetcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+)
+
+// newTrendServer creates a mock server answering trend requests with the given
+// response, and records the query of the last request in query.
+func newTrendServer(
+	t *testing.T,
+	response datafetcher.TrendResponse,
+	query *string,
+) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/trends/test_machine/test_point/test_pmode/" {
+				http.NotFound(w, r)
+				return
+			}
+			*query = r.URL.RawQuery
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+		}),
+	)
+}
+
+// TestGetTrendSuccess tests the retrieval and decoding of a trend, with and without a time range.
+func TestGetTrendSuccess(t *testing.T) {
+	mockTrendResponse := datafetcher.TrendResponse{
+		Timestamps: []int64{1554907724, 1554907784, 1554907844, 1554907904},
+		Parameters: []datafetcher.TrendParameterResponse{
+			{Name: "overall", Units: "g", RawData: "eJzjYhBhkGPQYAAAAZgAZQ==", Factor: 0.5},
+			{Name: "bias", Units: "V", RawData: "eJxjZPj//389QwMAEP4D/g==", Factor: 1},
+		},
+	}
+
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	testCases := []struct {
+		name          string
+		timeRange     datafetcher.TimeRange
+		expectedQuery string
+		expected      datafetcher.Trend
+	}{
+		{
+			name:          "Unbounded",
+			timeRange:     datafetcher.TimeRange{},
+			expectedQuery: "",
+			expected: datafetcher.Trend{
+				Times: []time.Time{
+					time.Unix(1554907724, 0).UTC(),
+					time.Unix(1554907784, 0).UTC(),
+					time.Unix(1554907844, 0).UTC(),
+					time.Unix(1554907904, 0).UTC(),
+				},
+				Parameters: []datafetcher.TrendParameter{
+					{Name: "overall", Units: "g", Values: []float64{5, 10, 15, 20}},
+					{Name: "bias", Units: "V", Values: []float64{1, -1, 32767, -32768}},
+				},
+			},
+		},
+		{
+			name: "Bounded",
+			timeRange: datafetcher.TimeRange{
+				From: time.Unix(1554907784, 0),
+				To:   time.Unix(1554907844, 0),
+			},
+			expectedQuery: "from=1554907784&to=1554907844",
+			expected: datafetcher.Trend{
+				Times: []time.Time{
+					time.Unix(1554907784, 0).UTC(),
+					time.Unix(1554907844, 0).UTC(),
+				},
+				Parameters: []datafetcher.TrendParameter{
+					{Name: "overall", Units: "g", Values: []float64{10, 15}},
+					{Name: "bias", Units: "V", Values: []float64{-1, 32767}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var query string
+			mock_server := newTrendServer(t, mockTrendResponse, &query)
+			defer mock_server.Close()
+
+			fetcher := newTestFetcher(t, mock_server.URL)
+
+			trend, err := fetcher.GetTrend(context.Background(), params, tc.timeRange)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if query != tc.expectedQuery {
+				t.Errorf("expected query %q, got %q", tc.expectedQuery, query)
+			}
+
+			if !reflect.DeepEqual(trend, tc.expected) {
+				t.Errorf("expected trend %+v, got %+v", tc.expected, trend)
+			}
+
+			overall, ok := trend.Parameter("overall")
+			if !ok || !reflect.DeepEqual(overall, tc.expected.Parameters[0]) {
+				t.Errorf(
+					"expected overall parameter %+v, got %+v",
+					tc.expected.Parameters[0],
+					overall,
+				)
+			}
+
+			if _, ok := trend.Parameter("missing"); ok {
+				t.Errorf("expected missing parameter not to be found")
+			}
+		})
+	}
+}
+
+// TestGetTrendDecodeErrors tests that malformed trend payloads are reported as ErrDecode.
+func TestGetTrendDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response datafetcher.TrendResponse
+	}{
+		{
+			name: "Invalid Data",
+			response: datafetcher.TrendResponse{
+				Timestamps: []int64{1554907724},
+				Parameters: []datafetcher.TrendParameterResponse{
+					{Name: "overall", RawData: "invalid_data", Factor: 1},
+				},
+			},
+		},
+		{
+			name: "Length Mismatch",
+			response: datafetcher.TrendResponse{
+				Timestamps: []int64{1554907724, 1554907784, 1554907844, 1554907904},
+				Parameters: []datafetcher.TrendParameterResponse{
+					{Name: "overall", RawData: "eJzjYhBhkGMAAADOAD0=", Factor: 1},
+				},
+			},
+		},
+	}
+
+	params := datafetcher.NewPmodeUrlParams("test_machine", "test_point", "test_pmode")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var query string
+			mock_server := newTrendServer(t, tc.response, &query)
+			defer mock_server.Close()
+
+			fetcher := newTestFetcher(t, mock_server.URL)
+
+			trend, err := fetcher.GetTrend(context.Background(), params, datafetcher.TimeRange{})
+			if !errors.Is(err, datafetcher.ErrDecode) {
+				t.Errorf("expected ErrDecode, got %v", err)
+			}
+
+			if !reflect.DeepEqual(trend, datafetcher.Trend{}) {
+				t.Errorf("expected empty trend, got %+v", trend)
+			}
+		})
+	}
+}
@@ -1,50 +1,63 @@
 package datafetcher
 
-type BaseUrlParams struct {
-	Host     string
-	User     string
-	Password string
-}
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
 
+// PmodeUrlParams identifies a processing mode of a measurement point of a machine.
+// The host and credentials are not part of it: they belong to the Client the request
+// is made through.
 type PmodeUrlParams struct {
-	BaseUrlParams
 	Machine string
 	Point   string
 	Pmode   string
 }
 
 // NewPmodeUrlParams creates a new instance of PmodeUrlParams with the provided
-// host, machine, point, pmode, user, and password values. It initializes the
-// BaseUrlParams with the host, user, and password, and sets the machine, point,
-// and pmode fields specific to PmodeUrlParams.
+// machine, point and pmode values.
 //
 // Parameters:
-//   - host: The host address.
 //   - machine: The machine identifier.
 //   - point: The point identifier.
 //   - pmode: The pmode value.
-//   - user: The username for authentication.
-//   - password: The password for authentication.
 //
 // Returns:
 //
 //	A PmodeUrlParams struct populated with the provided values.
-func NewPmodeUrlParams(host, machine, point, pmode, user, password string) PmodeUrlParams {
+func NewPmodeUrlParams(machine, point, pmode string) PmodeUrlParams {
 	return PmodeUrlParams{
-		BaseUrlParams: BaseUrlParams{
-			Host:     host,
-			User:     user,
-			Password: password,
-		},
 		Machine: machine,
 		Point:   point,
 		Pmode:   pmode,
 	}
 }
 
+// path returns the "/machine/point/pmode" URL path segment identifying the
+// processing mode, with each element escaped.
+func (p PmodeUrlParams) path() string {
+	return fmt.Sprintf(
+		"/%s/%s/%s",
+		url.PathEscape(p.Machine),
+		url.PathEscape(p.Point),
+		url.PathEscape(p.Pmode),
+	)
+}
+
+// PmodeUrlTimeParams identifies a record of a processing mode by its acquisition time.
+// The time is given either by Time or, if Time is zero, by DateTime.
 type PmodeUrlTimeParams struct {
 	PmodeUrlParams
+	// DateTime is the acquisition time of the record in any of the forms accepted by
+	// timeconversion.ParseTime, such as "2019-04-11T18:25:54", "2019-04-11T20:25:54+02:00",
+	// "1555007154" or "now-2h". Times without time zone are read in Location.
 	DateTime string
+	// Location is the time zone DateTime is read in when it has none, or nil for UTC.
+	Location *time.Location
+	// Time is the acquisition time of the record. If it is not zero, DateTime and
+	// Location are ignored.
+	Time time.Time
 }
 
 // NewPmodeUrlTimeParams creates a new instance of PmodeUrlTimeParams with the provided
@@ -52,22 +65,36 @@ type PmodeUrlTimeParams struct {
 // and sets the DateTime field to the specified time.
 //
 // Parameters:
-//   - host: The host address.
 //   - machine: The machine identifier.
 //   - point: The point identifier.
 //   - pmode: The mode of operation.
 //   - time: The time value to be set in the DateTime field.
-//   - user: The username for authentication.
-//   - password: The password for authentication.
 //
 // Returns:
 //
 //	A PmodeUrlTimeParams struct populated with the provided values.
-func NewPmodeUrlTimeParams(
-	host, machine, point, pmode, time, user, password string,
-) PmodeUrlTimeParams {
+func NewPmodeUrlTimeParams(machine, point, pmode, time string) PmodeUrlTimeParams {
 	return PmodeUrlTimeParams{
-		PmodeUrlParams: NewPmodeUrlParams(host, machine, point, pmode, user, password),
+		PmodeUrlParams: NewPmodeUrlParams(machine, point, pmode),
 		DateTime:       time,
 	}
 }
+
+// NewPmodeUrlTimeParamsAt creates a new instance of PmodeUrlTimeParams identifying the
+// record acquired at t, in any time zone.
+//
+// Parameters:
+//   - machine: The machine identifier.
+//   - point: The point identifier.
+//   - pmode: The mode of operation.
+//   - t: The acquisition time of the record.
+//
+// Returns:
+//
+//	A PmodeUrlTimeParams struct populated with the provided values.
+func NewPmodeUrlTimeParamsAt(machine, point, pmode string, t time.Time) PmodeUrlTimeParams {
+	return PmodeUrlTimeParams{
+		PmodeUrlParams: NewPmodeUrlParams(machine, point, pmode),
+		Time:           t,
+	}
+}
@@ -0,0 +1,477 @@
+// Package fleet addresses a set of named T8 devices as a whole.
+//
+// A Fleet holds a registry of devices, each reached through its own
+// datafetcher.Client with its own credentials, TLS settings and limits. Records are
+// addressed by device name plus the usual machine, point and processing mode, and
+// fleet-wide queries, such as the latest waveform of every processing mode of every
+// device, are fanned out concurrently. Failures of individual devices or processing
+// modes do not stop such queries: they are reported together, joined with errors.Join,
+// along with the results that could be fetched.
+package fleet
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// DefaultWorkers is the number of concurrent requests of fleet-wide queries when
+// WithWorkers is not used.
+const DefaultWorkers = 8
+
+// ErrUnknownDevice is reported when a device name is not registered in the Fleet.
+var ErrUnknownDevice = errors.New("unknown device")
+
+// Source is what the records of a device are read through. datafetcher.HttpDataFetcher
+// implements it.
+type Source interface {
+	datafetcher.RecordSource
+
+	// Discover retrieves the machines configured in the device, with their points and
+	// processing modes.
+	Discover(ctx context.Context) ([]datafetcher.Machine, error)
+
+	// GetTrend retrieves the trend of a processing mode within a time range.
+	GetTrend(
+		ctx context.Context,
+		urlParams datafetcher.PmodeUrlParams,
+		timeRange datafetcher.TimeRange,
+	) (datafetcher.Trend, error)
+}
+
+// DeviceError is an error reported by a device of the Fleet.
+type DeviceError struct {
+	// Device is the name of the device.
+	Device string
+	// Err is the error reported by the device.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DeviceError) Error() string {
+	return fmt.Sprintf("device %s: %v", e.Device, e.Err)
+}
+
+// Unwrap returns the underlying error, so that DeviceError matches the sentinel errors
+// of datafetcher with errors.Is.
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures a Fleet created with New.
+type Option func(*Fleet)
+
+// WithWorkers sets the maximum number of concurrent requests of fleet-wide queries,
+// across all devices. Values below 1 are ignored. Per-device limits can be enforced
+// too, with datafetcher.WithLimits.
+func WithWorkers(workers int) Option {
+	return func(f *Fleet) {
+		if workers > 0 {
+			f.workers = workers
+		}
+	}
+}
+
+// Fleet is a registry of named T8 devices. It is safe for concurrent use by multiple
+// goroutines.
+type Fleet struct {
+	workers int
+
+	mu      sync.RWMutex
+	devices map[string]Source
+}
+
+// New creates an empty Fleet.
+func New(opts ...Option) *Fleet {
+	f := &Fleet{workers: DefaultWorkers, devices: make(map[string]Source)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Add registers the device name, reached at host through a datafetcher.Client created
+// with opts, which typically hold its credentials.
+//
+// Parameters:
+//   - name: The name the device is addressed by.
+//   - host: The base URL of the T8 REST API of the device.
+//   - opts: The options of the client, such as datafetcher.WithCredentials.
+//
+// Returns:
+//
+//	An error if the name is empty or already registered, or the client cannot be created.
+func (f *Fleet) Add(name, host string, opts ...datafetcher.ClientOption) error {
+	client, err := datafetcher.NewClient(host, opts...)
+	if err != nil {
+		return &DeviceError{Device: name, Err: err}
+	}
+	return f.AddSource(name, datafetcher.NewHttpDataFetcher(client))
+}
+
+// AddSource registers the device name, read through source.
+func (f *Fleet) AddSource(name string, source Source) error {
+	if name == "" {
+		return errors.New("device name must not be empty")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.devices[name]; ok {
+		return fmt.Errorf("device %s is already registered", name)
+	}
+	f.devices[name] = source
+	return nil
+}
+
+// Remove unregisters the device name, if it is registered.
+func (f *Fleet) Remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.devices, name)
+}
+
+// Devices returns the names of the registered devices, sorted.
+func (f *Fleet) Devices() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, 0, len(f.devices))
+	for name := range f.devices {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Source returns the Source of the device name, or an error matching ErrUnknownDevice.
+func (f *Fleet) Source(name string) (Source, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	source, ok := f.devices[name]
+	if !ok {
+		return nil, &DeviceError{Device: name, Err: ErrUnknownDevice}
+	}
+	return source, nil
+}
+
+// GetWaveform retrieves a waveform of the device name. See
+// datafetcher.DataFetcher.GetWaveformContext.
+func (f *Fleet) GetWaveform(
+	ctx context.Context,
+	name string,
+	urlParams datafetcher.PmodeUrlTimeParams,
+) (waveforms.Waveform, error) {
+	return withSource(f, name, func(source Source) (waveforms.Waveform, error) {
+		return source.GetWaveformContext(ctx, urlParams)
+	})
+}
+
+// GetSpectrum retrieves a spectrum of the device name. See
+// datafetcher.DataFetcher.GetSpectrumContext.
+func (f *Fleet) GetSpectrum(
+	ctx context.Context,
+	name string,
+	urlParams datafetcher.PmodeUrlTimeParams,
+) (spectra.Spectrum, error) {
+	return withSource(f, name, func(source Source) (spectra.Spectrum, error) {
+		return source.GetSpectrumContext(ctx, urlParams)
+	})
+}
+
+// ListWaveforms lists the waveforms of a processing mode of the device name. See
+// datafetcher.RecordLister.
+func (f *Fleet) ListWaveforms(
+	ctx context.Context,
+	name string,
+	urlParams datafetcher.PmodeUrlParams,
+	timeRange datafetcher.TimeRange,
+) ([]time.Time, error) {
+	return withSource(f, name, func(source Source) ([]time.Time, error) {
+		return source.ListWaveforms(ctx, urlParams, timeRange)
+	})
+}
+
+// ListSpectra lists the spectra of a processing mode of the device name. See
+// datafetcher.RecordLister.
+func (f *Fleet) ListSpectra(
+	ctx context.Context,
+	name string,
+	urlParams datafetcher.PmodeUrlParams,
+	timeRange datafetcher.TimeRange,
+) ([]time.Time, error) {
+	return withSource(f, name, func(source Source) ([]time.Time, error) {
+		return source.ListSpectra(ctx, urlParams, timeRange)
+	})
+}
+
+// GetTrend retrieves the trend of a processing mode of the device name.
+func (f *Fleet) GetTrend(
+	ctx context.Context,
+	name string,
+	urlParams datafetcher.PmodeUrlParams,
+	timeRange datafetcher.TimeRange,
+) (datafetcher.Trend, error) {
+	return withSource(f, name, func(source Source) (datafetcher.Trend, error) {
+		return source.GetTrend(ctx, urlParams, timeRange)
+	})
+}
+
+// withSource calls fetch with the Source of the device name, and wraps any error in a
+// DeviceError.
+func withSource[T any](f *Fleet, name string, fetch func(Source) (T, error)) (T, error) {
+	source, err := f.Source(name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	result, err := fetch(source)
+	if err != nil {
+		return result, &DeviceError{Device: name, Err: err}
+	}
+	return result, nil
+}
+
+// Configuration is the configuration of a device of the Fleet.
+type Configuration struct {
+	// Device is the name of the device.
+	Device string
+	// Machines are the machines configured in the device, with their points and
+	// processing modes.
+	Machines []datafetcher.Machine
+}
+
+// Discover retrieves the configuration of every device, concurrently.
+//
+// Returns:
+//   - []Configuration: The configurations that could be retrieved, sorted by device.
+//   - error: The errors of the devices that failed, as DeviceErrors joined with
+//     errors.Join, or nil.
+func (f *Fleet) Discover(ctx context.Context) ([]Configuration, error) {
+	return fanOut(ctx, f, f.Devices(), func(name string) (Configuration, error) {
+		machines, err := withSource(f, name, func(source Source) ([]datafetcher.Machine, error) {
+			return source.Discover(ctx)
+		})
+		return Configuration{Device: name, Machines: machines}, err
+	})
+}
+
+// Waveform is a waveform of a device of the Fleet. The machine, point and processing
+// mode it belongs to, and its acquisition time, are in its metadata.
+type Waveform struct {
+	Device string
+	waveforms.Waveform
+}
+
+// Spectrum is a spectrum of a device of the Fleet. The machine, point and processing
+// mode it belongs to, and its acquisition time, are in its metadata.
+type Spectrum struct {
+	Device string
+	spectra.Spectrum
+}
+
+// LatestWaveforms retrieves the latest waveform of every processing mode of every point
+// of every device. The configuration of the devices is discovered first, and then the
+// waveforms are listed and fetched concurrently.
+//
+// Returns:
+//   - []Waveform: The waveforms that could be retrieved, sorted by device, machine, point
+//     and processing mode. Processing modes without waveforms are skipped.
+//   - error: The errors of the devices and processing modes that failed, as DeviceErrors
+//     joined with errors.Join, or nil.
+func (f *Fleet) LatestWaveforms(ctx context.Context) ([]Waveform, error) {
+	results, err := latest(ctx, f, datafetcher.WaveformRecord,
+		func(source Source, urlParams datafetcher.PmodeUrlTimeParams) (Waveform, error) {
+			waveform, err := source.GetWaveformContext(ctx, urlParams)
+			return Waveform{Waveform: waveform}, err
+		},
+	)
+	for i := range results {
+		results[i].value.Device = results[i].device
+	}
+	return values(results), err
+}
+
+// LatestSpectra retrieves the latest spectrum of every processing mode of every point of
+// every device, like LatestWaveforms.
+func (f *Fleet) LatestSpectra(ctx context.Context) ([]Spectrum, error) {
+	results, err := latest(ctx, f, datafetcher.SpectrumRecord,
+		func(source Source, urlParams datafetcher.PmodeUrlTimeParams) (Spectrum, error) {
+			spectrum, err := source.GetSpectrumContext(ctx, urlParams)
+			return Spectrum{Spectrum: spectrum}, err
+		},
+	)
+	for i := range results {
+		results[i].value.Device = results[i].device
+	}
+	return values(results), err
+}
+
+// pmodeTask is a processing mode of a device, visited by a fleet-wide query.
+type pmodeTask struct {
+	device    string
+	source    Source
+	urlParams datafetcher.PmodeUrlParams
+}
+
+// pmodeResult is the result of a fleet-wide query for a processing mode.
+type pmodeResult[T any] struct {
+	device    string
+	urlParams datafetcher.PmodeUrlParams
+	value     T
+	found     bool
+}
+
+// latest fetches, with fetch, the latest record of the given kind of every processing
+// mode of the fleet.
+func latest[T any](
+	ctx context.Context,
+	f *Fleet,
+	kind datafetcher.RecordKind,
+	fetch func(Source, datafetcher.PmodeUrlTimeParams) (T, error),
+) ([]pmodeResult[T], error) {
+	configurations, discoverErr := f.Discover(ctx)
+
+	var tasks []pmodeTask
+	for _, configuration := range configurations {
+		source, err := f.Source(configuration.Device)
+		if err != nil {
+			// The device was removed while the query was running.
+			continue
+		}
+		for _, machine := range configuration.Machines {
+			for _, point := range machine.Points {
+				for _, pmode := range point.Pmodes {
+					tasks = append(tasks, pmodeTask{
+						device: configuration.Device,
+						source: source,
+						urlParams: datafetcher.NewPmodeUrlParams(
+							machine.Tag,
+							point.Tag,
+							pmode.Tag,
+						),
+					})
+				}
+			}
+		}
+	}
+
+	results, fetchErr := fanOut(ctx, f, tasks, func(task pmodeTask) (pmodeResult[T], error) {
+		result := pmodeResult[T]{device: task.device, urlParams: task.urlParams}
+
+		list := task.source.ListWaveforms
+		if kind == datafetcher.SpectrumRecord {
+			list = task.source.ListSpectra
+		}
+		times, err := list(ctx, task.urlParams, datafetcher.TimeRange{})
+		if err != nil || len(times) == 0 {
+			return result, taskError(task, kind, "listing", err)
+		}
+
+		urlParams := datafetcher.PmodeUrlTimeParams{
+			PmodeUrlParams: task.urlParams,
+			Time:           times[len(times)-1],
+		}
+		result.value, err = fetch(task.source, urlParams)
+		result.found = err == nil
+		return result, taskError(task, kind, "fetching", err)
+	})
+
+	results = slices.DeleteFunc(results, func(result pmodeResult[T]) bool {
+		return !result.found
+	})
+	slices.SortFunc(results, func(a, b pmodeResult[T]) int {
+		return cmp.Or(
+			cmp.Compare(a.device, b.device),
+			cmp.Compare(a.urlParams.Machine, b.urlParams.Machine),
+			cmp.Compare(a.urlParams.Point, b.urlParams.Point),
+			cmp.Compare(a.urlParams.Pmode, b.urlParams.Pmode),
+		)
+	})
+
+	return results, errors.Join(discoverErr, fetchErr)
+}
+
+// taskError wraps err, returned while doing action on the latest record of the given kind
+// of task, in a DeviceError. It returns nil if err is nil.
+func taskError(task pmodeTask, kind datafetcher.RecordKind, action string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DeviceError{
+		Device: task.device,
+		Err: fmt.Errorf(
+			"error %s %s records of %s/%s/%s: %w",
+			action,
+			kind,
+			task.urlParams.Machine,
+			task.urlParams.Point,
+			task.urlParams.Pmode,
+			err,
+		),
+	}
+}
+
+// values returns the values of results.
+func values[T any](results []pmodeResult[T]) []T {
+	out := make([]T, len(results))
+	for i, result := range results {
+		out[i] = result.value
+	}
+	return out
+}
+
+// fanOut calls do for every item, using up to f.workers goroutines, and returns the
+// results of the calls that succeeded, in the order of items, and the errors of the
+// others joined with errors.Join. Items not started when ctx is done fail with its error.
+func fanOut[I, T any](
+	ctx context.Context,
+	f *Fleet,
+	items []I,
+	do func(I) (T, error),
+) ([]T, error) {
+	results := make([]T, len(items))
+	errs := make([]error, len(items))
+	succeeded := make([]bool, len(items))
+
+	semaphore := make(chan struct{}, f.workers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			results[i], errs[i] = do(item)
+			succeeded[i] = errs[i] == nil
+		}()
+	}
+	wg.Wait()
+
+	var out []T
+	for i, result := range results {
+		if succeeded[i] {
+			out = append(out, result)
+		}
+	}
+	return out, errors.Join(errs...)
+}
@@ -0,0 +1,187 @@
+package fleet_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/fleet"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/t8test"
+)
+
+// fleetBase is the acquisition time of the first record of every mock server.
+var fleetBase = time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+
+// newDeviceServer creates a mock server holding, for each of the given points of
+// machine, waveforms and spectra acquired at fleetBase and one minute after it.
+func newDeviceServer(t *testing.T, machine string, points ...string) *t8test.Server {
+	t.Helper()
+
+	server := t8test.NewServer()
+	t.Cleanup(server.Close)
+
+	for _, point := range points {
+		for _, offset := range []time.Duration{0, time.Minute} {
+			waveform := t8test.SineWaveform(2560, 256, t8test.Tone{Frequency: 50, Amplitude: 1})
+			server.AddWaveform(machine, point, "AM1", fleetBase.Add(offset), waveform)
+			server.AddSpectrum(
+				machine,
+				point,
+				"AM1",
+				fleetBase.Add(offset),
+				spectra.SpectrumFromWaveform(waveform, 0, 1000),
+			)
+		}
+	}
+
+	return server
+}
+
+// newFleet creates a fleet of two devices: "north", with two points, and "south", with
+// one.
+func newFleet(t *testing.T) (*fleet.Fleet, *t8test.Server, *t8test.Server) {
+	t.Helper()
+
+	north := newDeviceServer(t, "pump", "DE", "NDE")
+	south := newDeviceServer(t, "fan", "DE")
+
+	f := fleet.New(fleet.WithWorkers(2))
+	for name, server := range map[string]*t8test.Server{"north": north, "south": south} {
+		if err := f.Add(name, server.URL); err != nil {
+			t.Fatalf("failed to add device %s: %v", name, err)
+		}
+	}
+
+	return f, north, south
+}
+
+// TestFleetRegistry tests registering and removing devices.
+func TestFleetRegistry(t *testing.T) {
+	f, _, _ := newFleet(t)
+
+	if devices := f.Devices(); len(devices) != 2 || devices[0] != "north" ||
+		devices[1] != "south" {
+		t.Errorf("expected devices [north south], got %v", devices)
+	}
+
+	if err := f.Add("north", "https://t8.example.com"); err == nil {
+		t.Errorf("expected error adding a duplicate device, got none")
+	}
+	if err := f.Add("", "https://t8.example.com"); err == nil {
+		t.Errorf("expected error adding a device without name, got none")
+	}
+	if err := f.Add("west", "://invalid"); err == nil {
+		t.Errorf("expected error adding a device with an invalid host, got none")
+	}
+
+	f.Remove("north")
+	if _, err := f.Source("north"); !errors.Is(err, fleet.ErrUnknownDevice) {
+		t.Errorf("expected ErrUnknownDevice, got %v", err)
+	}
+	if devices := f.Devices(); len(devices) != 1 || devices[0] != "south" {
+		t.Errorf("expected devices [south], got %v", devices)
+	}
+}
+
+// TestFleetDeviceOperations tests the operations addressed to a single device.
+func TestFleetDeviceOperations(t *testing.T) {
+	f, _, _ := newFleet(t)
+	ctx := t.Context()
+	params := datafetcher.NewPmodeUrlParams("pump", "NDE", "AM1")
+
+	times, err := f.ListWaveforms(ctx, "north", params, datafetcher.TimeRange{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(times) != 2 {
+		t.Fatalf("expected 2 waveforms, got %d", len(times))
+	}
+
+	waveform, err := f.GetWaveform(
+		ctx,
+		"north",
+		datafetcher.PmodeUrlTimeParams{PmodeUrlParams: params, Time: times[1]},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(waveform.Samples) != 256 || !waveform.Time.Equal(times[1]) {
+		t.Errorf("expected 256 samples at %v, got %d at %v",
+			times[1], len(waveform.Samples), waveform.Time)
+	}
+
+	if _, err := f.GetSpectrum(
+		ctx,
+		"south",
+		datafetcher.PmodeUrlTimeParams{PmodeUrlParams: params, Time: times[1]},
+	); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound from a device without the point, got %v", err)
+	}
+
+	if _, err := f.ListSpectra(
+		ctx,
+		"east",
+		params,
+		datafetcher.TimeRange{},
+	); !errors.Is(err, fleet.ErrUnknownDevice) {
+		t.Errorf("expected ErrUnknownDevice, got %v", err)
+	}
+}
+
+// TestFleetLatestWaveforms tests fetching the latest waveform of every processing mode
+// of every device.
+func TestFleetLatestWaveforms(t *testing.T) {
+	f, _, _ := newFleet(t)
+
+	results, err := f.LatestWaveforms(t.Context())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []struct{ device, machine, point string }{
+		{"north", "pump", "DE"},
+		{"north", "pump", "NDE"},
+		{"south", "fan", "DE"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d waveforms, got %d", len(expected), len(results))
+	}
+	for i, e := range expected {
+		result := results[i]
+		if result.Device != e.device || result.Machine != e.machine || result.Point != e.point {
+			t.Errorf("expected waveform %d of %v, got %s/%s/%s",
+				i, e, result.Device, result.Machine, result.Point)
+		}
+		if !result.Time.Equal(fleetBase.Add(time.Minute)) {
+			t.Errorf("expected the latest waveform, got one acquired at %v", result.Time)
+		}
+	}
+}
+
+// TestFleetPartialFailure tests that the failure of a device is reported along with the
+// results of the others.
+func TestFleetPartialFailure(t *testing.T) {
+	f, _, south := newFleet(t)
+	south.InjectFault(t8test.Fault{Status: http.StatusUnauthorized})
+
+	results, err := f.LatestSpectra(t.Context())
+	if len(results) != 2 {
+		t.Errorf("expected 2 spectra from the healthy device, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Device != "north" {
+			t.Errorf("expected spectra from north only, got one from %s", result.Device)
+		}
+	}
+
+	var deviceErr *fleet.DeviceError
+	if !errors.As(err, &deviceErr) || deviceErr.Device != "south" {
+		t.Fatalf("expected a DeviceError for south, got %v", err)
+	}
+	if !errors.Is(err, datafetcher.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
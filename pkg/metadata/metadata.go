@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Metadata describes the source of a T8 record: where and when it was acquired, and
+// how its values were scaled. It is embedded in waveforms.Waveform and spectra.Spectrum,
+// so that results can be labelled without keeping track of the request that produced
+// them. Fields the source does not report are left at their zero value.
+type Metadata struct {
+	// Machine is the tag of the machine the record belongs to.
+	Machine string
+	// Point is the tag of the measurement point the record was acquired at.
+	Point string
+	// Pmode is the tag of the processing mode that produced the record.
+	Pmode string
+	// Time is the acquisition time of the record, in UTC.
+	Time time.Time
+	// Factor is the scale factor applied to the raw integer values of the record.
+	Factor float64
+	// Units are the units of the scaled values, e.g. "g" or "mm/s".
+	Units string
+	// Speed is the rotating speed of the machine at acquisition time, in the units
+	// reported by the device, or nil if the device did not report it.
+	Speed *float64
+	// Extras holds the members of the record response that are not mapped to any other
+	// field, as raw JSON.
+	Extras map[string]json.RawMessage
+}
+
+// Label returns a short description of the record source, such as
+// "machine/point/pmode 2019-04-10T14:48:44Z", suitable for plot titles and file names.
+// Parts that are not known are omitted, so Label returns "" for an empty Metadata.
+func (m Metadata) Label() string {
+	var parts []string
+	for _, part := range []string{m.Machine, m.Point, m.Pmode} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	label := strings.Join(parts, "/")
+	if !m.Time.IsZero() {
+		label = strings.TrimSpace(label + " " + m.Time.UTC().Format(time.RFC3339))
+	}
+
+	return label
+}
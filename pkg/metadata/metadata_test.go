@@ -0,0 +1,53 @@
+package metadata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
+)
+
+// TestLabel tests labelling records with complete and partial metadata.
+func TestLabel(t *testing.T) {
+	acquired := time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		metadata metadata.Metadata
+		expected string
+	}{
+		{
+			name: "Complete",
+			metadata: metadata.Metadata{
+				Machine: "machine",
+				Point:   "point",
+				Pmode:   "pmode",
+				Time:    acquired,
+			},
+			expected: "machine/point/pmode 2019-04-10T14:48:44Z",
+		},
+		{
+			name:     "Without Time",
+			metadata: metadata.Metadata{Machine: "machine", Point: "point", Pmode: "pmode"},
+			expected: "machine/point/pmode",
+		},
+		{
+			name:     "Only Time",
+			metadata: metadata.Metadata{Time: acquired.In(time.FixedZone("CEST", 2*60*60))},
+			expected: "2019-04-10T14:48:44Z",
+		},
+		{
+			name:     "Empty",
+			metadata: metadata.Metadata{},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if label := tc.metadata.Label(); label != tc.expected {
+				t.Errorf("expected label %q, got %q", tc.expected, label)
+			}
+		})
+	}
+}
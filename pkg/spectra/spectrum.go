@@ -4,16 +4,22 @@ import (
 	"math"
 	"math/cmplx"
 
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
 	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
-	"gonum.org/v1/gonum/cmplxs"
 	"gonum.org/v1/gonum/dsp/fourier"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 )
 
+// Spectrum holds the magnitudes of a spectrum, the frequency of each bin, the
+// frequency range [Fmin, Fmax] it was requested or computed for, and the metadata of
+// the record it was obtained from.
 type Spectrum struct {
+	metadata.Metadata
 	Magnitudes  []float64
 	Frequencies []float64
+	Fmin        float64
+	Fmax        float64
 }
 
 // NewSpectrum creates a new Spectrum object with the given magnitudes and frequency range.
@@ -26,7 +32,8 @@ type Spectrum struct {
 //
 // Returns:
 //
-//	A Spectrum object containing the provided magnitudes and the calculated frequencies.
+//	A Spectrum object containing the provided magnitudes, the calculated frequencies and
+//	the frequency range.
 func NewSpectrum(magnitudes []float64, fmin, fmax float64) Spectrum {
 	// Calculate frequencies
 	frequencies := make([]float64, len(magnitudes))
@@ -39,11 +46,62 @@ func NewSpectrum(magnitudes []float64, fmin, fmax float64) Spectrum {
 	return Spectrum{
 		Magnitudes:  magnitudes,
 		Frequencies: frequencies,
+		Fmin:        fmin,
+		Fmax:        fmax,
 	}
 }
 
+// Scaling selects what the magnitudes of a spectrum computed from a waveform represent.
+type Scaling int
+
+const (
+	// RMS magnitudes are the RMS values of the sinusoidal components of the waveform, i.e.
+	// their amplitudes divided by sqrt(2). The DC component is its value.
+	RMS Scaling = iota
+	// Peak magnitudes are the amplitudes of the sinusoidal components of the waveform.
+	Peak
+)
+
+// Correction selects how the magnitudes of a spectrum computed from a waveform are
+// corrected for the window applied to it.
+type Correction int
+
+const (
+	// AmplitudeCorrection divides the magnitudes by the coherent gain of the window, so
+	// that a tone centred on a bin reads its amplitude or RMS value, whatever the window.
+	// It is the correction for reading discrete components, such as the running speed.
+	AmplitudeCorrection Correction = iota
+	// EnergyCorrection scales the magnitudes so that the square root of the sum of their
+	// squares, over the whole frequency range and with RMS scaling, is the RMS value of
+	// the waveform, whatever the window. It is the correction for broadband readings,
+	// such as the overall RMS of a band. It reads tones sqrt(ENBW) times lower than
+	// AmplitudeCorrection, where ENBW is the equivalent noise bandwidth of the window in
+	// bins.
+	EnergyCorrection
+)
+
+// SpectrumOptions configure how SpectrumFromWaveformWithOptions computes a spectrum.
+type SpectrumOptions struct {
+	// PreprocessOptions are the steps applied to the waveform before the FFT. The
+	// magnitudes are corrected for their Window.
+	waveforms.PreprocessOptions
+	// Scaling is what the magnitudes represent.
+	Scaling Scaling
+	// Correction is how the magnitudes are corrected for the window.
+	Correction Correction
+}
+
+// DefaultSpectrumOptions returns the options used to compare spectra computed from
+// waveforms with the ones of a T8: the default preprocessing, with a Hann window, and
+// amplitude-corrected RMS magnitudes.
+func DefaultSpectrumOptions() SpectrumOptions {
+	return SpectrumOptions{PreprocessOptions: waveforms.DefaultPreprocessOptions()}
+}
+
 // SpectrumFromWaveform computes the spectrum of a given waveform using FFT (Fast Fourier Transform)
-// and filters the resulting frequencies and magnitudes within a specified range.
+// and filters the resulting frequencies and magnitudes within a specified range. The samples are
+// transformed as they are, i.e. with a rectangular window, and the magnitudes are RMS values. Use
+// SpectrumFromWaveformWithOptions to window the samples.
 //
 // Parameters:
 //   - waveform: A waveforms.Waveform struct containing the waveform data.
@@ -51,39 +109,85 @@ func NewSpectrum(magnitudes []float64, fmin, fmax float64) Spectrum {
 //   - fmax: The maximum frequency of interest in Hz.
 //
 // Returns:
-//   - A Spectrum struct containing the magnitudes and corresponding frequencies within the specified range.
+//   - A Spectrum struct containing the magnitudes and corresponding frequencies within the specified range,
+//     which is recorded in its Fmin and Fmax fields. It carries a copy of the metadata of the waveform.
 func SpectrumFromWaveform(waveform waveforms.Waveform, fmin, fmax float64) Spectrum {
-	// Perform FFT on the waveform
-	fft := fourier.NewFFT(len(waveform.Samples))
-	spectrum := fft.Coefficients(nil, waveform.Samples)
-	cmplxs.Scale(complex(math.Sqrt(2), 0), spectrum)
-
-	// Calculate magnitudes and frequencies
-	magnitudes := make([]float64, len(spectrum))
-	frequencies := make([]float64, len(spectrum))
-	for i, c := range spectrum {
-		magnitudes[i] = cmplx.Abs(c) / float64(len(spectrum))
-		frequencies[i] = float64(i) * waveform.SampleRate / float64(len(waveform.Samples))
+	return SpectrumFromWaveformWithOptions(waveform, fmin, fmax, SpectrumOptions{})
+}
+
+// SpectrumFromWaveformWithOptions computes the spectrum of a given waveform like
+// SpectrumFromWaveform, preprocessing it first and scaling and correcting the magnitudes
+// for the window as set by opts. The samples of the waveform are not modified.
+//
+// Parameters:
+//   - waveform: A waveforms.Waveform struct containing the waveform data.
+//   - fmin: The minimum frequency of interest in Hz.
+//   - fmax: The maximum frequency of interest in Hz.
+//   - opts: The preprocessing, scaling and correction, e.g. DefaultSpectrumOptions().
+//
+// Returns:
+//   - A Spectrum struct containing the magnitudes and corresponding frequencies within the specified range,
+//     which is recorded in its Fmin and Fmax fields. It carries a copy of the metadata of the waveform.
+func SpectrumFromWaveformWithOptions(
+	waveform waveforms.Waveform,
+	fmin, fmax float64,
+	opts SpectrumOptions,
+) Spectrum {
+	result := Spectrum{Metadata: waveform.Metadata, Fmin: fmin, Fmax: fmax}
+
+	// The window spans the acquired samples only, before any padding
+	acquired := len(waveform.Samples)
+	if acquired == 0 {
+		return result
 	}
+	sum := waveforms.CoherentGain(opts.Window, acquired) * float64(acquired)
+	sumOfSquares := waveforms.EquivalentNoiseBandwidth(opts.Window, acquired) * sum * sum /
+		float64(acquired)
+
+	// Perform FFT on the preprocessed waveform
+	samples := waveform.Preprocess(opts.PreprocessOptions).Samples
+	n := len(samples)
+	fft := fourier.NewFFT(n)
+	coefficients := fft.Coefficients(nil, samples)
 
-	// Filter the spectrum and frequencies within the specified range
-	var filteredSpectrum []float64
-	var filteredFreqs []float64
-	for i, freq := range frequencies {
-		if freq >= fmin && freq <= fmax {
-			filteredSpectrum = append(filteredSpectrum, magnitudes[i])
-			filteredFreqs = append(filteredFreqs, freq)
+	for i, c := range coefficients {
+		freq := float64(i) * waveform.SampleRate / float64(n)
+		if freq < fmin || freq > fmax {
+			continue
 		}
-	}
 
-	return Spectrum{
-		Frequencies: filteredFreqs,
-		Magnitudes:  filteredSpectrum,
+		// Every bin but DC and Nyquist holds half the power of its component, the other
+		// half being at the mirrored negative frequency
+		sides := 2.0
+		if i == 0 || 2*i == n {
+			sides = 1
+		}
+
+		var rms float64
+		switch opts.Correction {
+		case EnergyCorrection:
+			// By Parseval's theorem, scaled by the power of the window
+			rms = cmplx.Abs(c) * math.Sqrt(sides/(float64(n)*sumOfSquares))
+		default:
+			// The amplitude of a tone is sides*|c|/sum, and its RMS value sqrt(sides)
+			// times lower
+			rms = cmplx.Abs(c) * math.Sqrt(sides) / sum
+		}
+
+		magnitude := rms
+		if opts.Scaling == Peak {
+			magnitude *= math.Sqrt(sides)
+		}
+
+		result.Magnitudes = append(result.Magnitudes, magnitude)
+		result.Frequencies = append(result.Frequencies, freq)
 	}
+
+	return result
 }
 
-// Plot genera una gráfica del espectro actual.
-func (spectrum Spectrum) Plot(fmin, fmax float64) (*plot.Plot, error) {
+// Plot genera una gráfica del espectro actual, limitando el eje X a su rango de frecuencias.
+func (spectrum Spectrum) Plot() (*plot.Plot, error) {
 	p := plot.New()
 
 	pts := make(plotter.XYs, len(spectrum.Magnitudes))
@@ -99,8 +203,18 @@ func (spectrum Spectrum) Plot(fmin, fmax float64) (*plot.Plot, error) {
 
 	p.Add(line)
 	p.Title.Text = "Spectrum"
+	if label := spectrum.Label(); label != "" {
+		p.Title.Text += " " + label
+	}
 	p.X.Label.Text = "Frequency (Hz)"
 	p.Y.Label.Text = "Magnitude"
+	if spectrum.Units != "" {
+		p.Y.Label.Text += " (" + spectrum.Units + ")"
+	}
+	if spectrum.Fmax > spectrum.Fmin {
+		p.X.Min = spectrum.Fmin
+		p.X.Max = spectrum.Fmax
+	}
 
 	return p, nil
 }
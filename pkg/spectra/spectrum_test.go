@@ -0,0 +1,148 @@
+package spectra_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// sineWaveform creates a waveform of the given number of samples holding a sine of the
+// given frequency and amplitude.
+func sineWaveform(
+	sampleRate float64,
+	samples int,
+	frequency, amplitude float64,
+) waveforms.Waveform {
+	values := make([]float64, samples)
+	for i := range values {
+		values[i] = amplitude * math.Sin(2*math.Pi*frequency*float64(i)/sampleRate)
+	}
+	return waveforms.Waveform{Samples: values, SampleRate: sampleRate}
+}
+
+// peak returns the largest magnitude of spectrum and its frequency.
+func peak(spectrum spectra.Spectrum) (float64, float64) {
+	var magnitude, frequency float64
+	for i, m := range spectrum.Magnitudes {
+		if m > magnitude {
+			magnitude, frequency = m, spectrum.Frequencies[i]
+		}
+	}
+	return magnitude, frequency
+}
+
+// overallRMS returns the square root of the sum of the squared magnitudes of spectrum.
+func overallRMS(spectrum spectra.Spectrum) float64 {
+	var sum float64
+	for _, m := range spectrum.Magnitudes {
+		sum += m * m
+	}
+	return math.Sqrt(sum)
+}
+
+// TestSpectrumWindowCorrection tests that a tone reads its RMS value with amplitude
+// correction, and the overall RMS of the waveform with energy correction, whatever the
+// window.
+func TestSpectrumWindowCorrection(t *testing.T) {
+	// 50 Hz falls on bin 40 of 2048 samples at 2560 Hz.
+	waveform := sineWaveform(2560, 2048, 50, 2)
+	expectedRMS := 2 / math.Sqrt2
+
+	testCases := []struct {
+		name   string
+		window waveforms.Window
+	}{
+		{name: "Rectangular", window: waveforms.Rectangular},
+		{name: "Hann", window: waveforms.Hann},
+		{name: "Hamming", window: waveforms.Hamming},
+		{name: "Flat Top", window: waveforms.FlatTop},
+		{name: "Blackman-Harris", window: waveforms.BlackmanHarris},
+		{name: "Kaiser", window: waveforms.Kaiser(8.6)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := spectra.DefaultSpectrumOptions()
+			opts.Window = tc.window
+
+			magnitude, frequency := peak(
+				spectra.SpectrumFromWaveformWithOptions(waveform, 0, 1280, opts),
+			)
+			if frequency != 50 || math.Abs(magnitude-expectedRMS) > 1e-2*expectedRMS {
+				t.Errorf(
+					"expected %v at 50 Hz with amplitude correction, got %v at %v Hz",
+					expectedRMS,
+					magnitude,
+					frequency,
+				)
+			}
+
+			opts.Correction = spectra.EnergyCorrection
+			spectrum := spectra.SpectrumFromWaveformWithOptions(waveform, 0, 1280, opts)
+			if got := overallRMS(spectrum); math.Abs(got-expectedRMS) > 1e-2*expectedRMS {
+				t.Errorf("expected overall RMS %v with energy correction, got %v", expectedRMS, got)
+			}
+		})
+	}
+}
+
+// TestSpectrumScaling tests the exact magnitudes of a rectangular spectrum.
+func TestSpectrumScaling(t *testing.T) {
+	waveform := sineWaveform(2560, 2048, 50, 2)
+	for i := range waveform.Samples {
+		waveform.Samples[i] += 3
+	}
+
+	testCases := []struct {
+		name     string
+		scaling  spectra.Scaling
+		dc, tone float64
+	}{
+		{name: "RMS", scaling: spectra.RMS, dc: 3, tone: math.Sqrt2},
+		{name: "Peak", scaling: spectra.Peak, dc: 3, tone: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spectrum := spectra.SpectrumFromWaveformWithOptions(
+				waveform,
+				0,
+				100,
+				spectra.SpectrumOptions{Scaling: tc.scaling},
+			)
+
+			if len(spectrum.Magnitudes) != 81 {
+				t.Fatalf("expected 81 bins up to 100 Hz, got %d", len(spectrum.Magnitudes))
+			}
+			if math.Abs(spectrum.Magnitudes[0]-tc.dc) > 1e-9 {
+				t.Errorf("expected DC magnitude %v, got %v", tc.dc, spectrum.Magnitudes[0])
+			}
+			if math.Abs(spectrum.Magnitudes[40]-tc.tone) > 1e-9 {
+				t.Errorf("expected tone magnitude %v, got %v", tc.tone, spectrum.Magnitudes[40])
+			}
+		})
+	}
+}
+
+// TestSpectrumFromWaveformKeepsSamples tests that computing a windowed spectrum leaves the
+// samples of the waveform untouched.
+func TestSpectrumFromWaveformKeepsSamples(t *testing.T) {
+	waveform := sineWaveform(2560, 1000, 50, 1)
+	first := waveform.Samples[1]
+
+	spectrum := spectra.SpectrumFromWaveformWithOptions(
+		waveform,
+		0,
+		1280,
+		spectra.DefaultSpectrumOptions(),
+	)
+
+	if waveform.Samples[1] != first || len(waveform.Samples) != 1000 {
+		t.Errorf("expected the samples of the waveform to be kept")
+	}
+	if len(spectrum.Magnitudes) != 513 {
+		t.Errorf("expected 513 bins of the padded waveform, got %d", len(spectrum.Magnitudes))
+	}
+}
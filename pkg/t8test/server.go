@@ -0,0 +1,614 @@
+// Package t8test provides a mock of the REST API of a T8 device, for end-to-end tests
+// of code talking to T8 devices, such as the datafetcher package.
+//
+// A Server is populated with waveforms and spectra, e.g. synthetic signals created with
+// SineWaveform, which it encodes and serves like a T8 does. It also serves record
+// listings, trends and the machine, point and processing mode configuration derived
+// from the stored records, and can require basic authentication and inject latency and
+// failures into its responses.
+package t8test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/codec"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// TrendParameter is the name of the trend parameter served by a Server for every
+// processing mode: the RMS value of each stored waveform.
+const TrendParameter = "rms"
+
+// Fault describes a failure injected into the responses of a Server.
+type Fault struct {
+	// PathPrefix restricts the fault to the requests whose path starts with it. An empty
+	// prefix matches every request.
+	PathPrefix string
+	// Status is the status code of the failed responses. Zero closes the connection
+	// without responding, as a network failure would.
+	Status int
+	// RetryAfter, if not empty, is sent as the Retry-After header of the failed responses.
+	RetryAfter string
+	// Count is the number of requests that fail. Zero makes every matching request fail
+	// until ClearFaults is called.
+	Count int
+}
+
+// Option configures a Server created with NewServer.
+type Option func(*Server)
+
+// WithBasicAuth makes the server reject the requests that do not carry the given
+// credentials with 401 Unauthorized.
+func WithBasicAuth(user, password string) Option {
+	return func(s *Server) {
+		s.user = user
+		s.password = password
+		s.auth = true
+	}
+}
+
+// WithLatency delays every response by d. See Server.SetLatency.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// Server is a mock T8 REST API, listening on a local address. Its methods are safe for
+// concurrent use, also while requests are served.
+type Server struct {
+	// URL is the base URL of the API, e.g. "http://127.0.0.1:41234", to be used as the
+	// host of the clients under test.
+	URL string
+
+	server   *httptest.Server
+	user     string
+	password string
+	auth     bool
+
+	mu       sync.Mutex
+	latency  time.Duration
+	faults   []*Fault
+	requests int
+	inFlight int
+	peak     int
+	pmodes   map[pmodeKey]*pmodeRecords
+}
+
+// pmodeKey identifies a processing mode.
+type pmodeKey struct {
+	machine, point, pmode string
+}
+
+// pmodeRecords holds the records stored for a processing mode, and the configuration
+// of the processing mode derived from them.
+type pmodeRecords struct {
+	waves      map[int64]storedRecord
+	spectra    map[int64]storedRecord
+	units      string
+	sampleRate float64
+	samples    int
+	minFreq    float64
+	maxFreq    float64
+}
+
+// storedRecord is a record as served by a Server.
+type storedRecord struct {
+	body []byte
+	rms  float64
+}
+
+// NewServer starts a Server without records. It must be closed with Close.
+func NewServer(opts ...Option) *Server {
+	s := &Server{pmodes: map[pmodeKey]*pmodeRecords{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /waves/{machine}/{point}/{pmode}/{$}", s.handleListWaves)
+	mux.HandleFunc("GET /waves/{machine}/{point}/{pmode}/{timestamp}", s.handleGetWave)
+	mux.HandleFunc("GET /spectra/{machine}/{point}/{pmode}/{$}", s.handleListSpectra)
+	mux.HandleFunc("GET /spectra/{machine}/{point}/{pmode}/{timestamp}", s.handleGetSpectrum)
+	mux.HandleFunc("GET /trends/{machine}/{point}/{pmode}/{$}", s.handleTrend)
+	mux.HandleFunc("GET /machines/{$}", s.handleMachines)
+	mux.HandleFunc("GET /machines/{machine}/points/{$}", s.handlePoints)
+	mux.HandleFunc("GET /machines/{machine}/points/{point}/pmodes/{$}", s.handlePmodes)
+
+	s.server = httptest.NewServer(s.middleware(mux))
+	s.URL = s.server.URL
+
+	return s
+}
+
+// Close shuts down the server, blocking until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetLatency delays every response by d, e.g. to exercise timeouts and cancellation.
+// A delayed request whose client gives up is abandoned.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// InjectFault makes the requests matching fault fail. Faults are matched in the order
+// they were injected, and a fault stops matching once it made Count requests fail.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// RequestCount returns the number of requests received by the server, including the
+// failed ones.
+func (s *Server) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// MaxConcurrentRequests returns the highest number of requests the server has been
+// handling at the same time, including their latency, e.g. to check the concurrency
+// limits of a client.
+func (s *Server) MaxConcurrentRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak
+}
+
+// AddWaveform stores a waveform, acquired at t, for a processing mode. The samples,
+// which must be finite, are quantized and encoded like a T8 does, so the served samples
+// differ slightly from the given ones. The units and speed of the waveform metadata are
+// served as well, and the sample rate and number of samples are reported as the
+// configuration of the processing mode.
+func (s *Server) AddWaveform(
+	machine, point, pmode string,
+	t time.Time,
+	waveform waveforms.Waveform,
+) {
+	data, factor := encode(waveform.Samples)
+	body := mustMarshal(map[string]any{
+		"data":        data,
+		"factor":      factor,
+		"sample_rate": waveform.SampleRate,
+		"units":       waveform.Units,
+		"speed":       waveform.Speed,
+	})
+
+	sumSquares := 0.0
+	for _, v := range waveform.Samples {
+		sumSquares += v * v
+	}
+	rms := 0.0
+	if len(waveform.Samples) > 0 {
+		rms = math.Sqrt(sumSquares / float64(len(waveform.Samples)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records(pmodeKey{machine, point, pmode})
+	records.waves[t.Unix()] = storedRecord{body: body, rms: rms}
+	records.sampleRate = waveform.SampleRate
+	records.samples = len(waveform.Samples)
+	if waveform.Units != "" {
+		records.units = waveform.Units
+	}
+}
+
+// AddSpectrum stores a spectrum, acquired at t, for a processing mode. The magnitudes,
+// which must be finite, are quantized and encoded like a T8 does. The frequency range
+// served is that of the frequencies of the spectrum, if any, or otherwise its Fmin and
+// Fmax, and is reported as the configuration of the processing mode.
+func (s *Server) AddSpectrum(machine, point, pmode string, t time.Time, spectrum spectra.Spectrum) {
+	minFreq, maxFreq := spectrum.Fmin, spectrum.Fmax
+	if len(spectrum.Frequencies) > 0 {
+		minFreq = spectrum.Frequencies[0]
+		maxFreq = spectrum.Frequencies[len(spectrum.Frequencies)-1]
+	}
+
+	data, factor := encode(spectrum.Magnitudes)
+	body := mustMarshal(map[string]any{
+		"data":     data,
+		"factor":   factor,
+		"min_freq": minFreq,
+		"max_freq": maxFreq,
+		"units":    spectrum.Units,
+		"speed":    spectrum.Speed,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records(pmodeKey{machine, point, pmode})
+	records.spectra[t.Unix()] = storedRecord{body: body}
+	records.minFreq = minFreq
+	records.maxFreq = maxFreq
+	if spectrum.Units != "" {
+		records.units = spectrum.Units
+	}
+}
+
+// records returns the records of a processing mode, creating them if needed. It must
+// be called with s.mu held.
+func (s *Server) records(key pmodeKey) *pmodeRecords {
+	records, ok := s.pmodes[key]
+	if !ok {
+		records = &pmodeRecords{
+			waves:   map[int64]storedRecord{},
+			spectra: map[int64]storedRecord{},
+		}
+		s.pmodes[key] = records
+	}
+	return records
+}
+
+// middleware counts requests, including those in flight, and applies the latency, the injected faults and the
+// authentication requirements before handing the request to next.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.inFlight++
+		s.peak = max(s.peak, s.inFlight)
+		latency := s.latency
+		fault := s.matchFault(r.URL.Path)
+		s.mu.Unlock()
+
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+
+		if latency > 0 {
+			timer := time.NewTimer(latency)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fault != nil {
+			writeFault(w, fault)
+			return
+		}
+
+		if s.auth {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != s.user || password != s.password {
+				w.Header().Set("WWW-Authenticate", `Basic realm="T8"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchFault returns a copy of the first fault matching path, if any, and counts the
+// failure. It must be called with s.mu held.
+func (s *Server) matchFault(path string) *Fault {
+	for i, fault := range s.faults {
+		if !strings.HasPrefix(path, fault.PathPrefix) {
+			continue
+		}
+
+		matched := *fault
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return &matched
+	}
+	return nil
+}
+
+// writeFault fails a request as described by fault.
+func writeFault(w http.ResponseWriter, fault *Fault) {
+	if fault.Status == 0 {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			panic("t8test: response writer does not support closing the connection")
+		}
+		conn, _, err := hijacker.Hijack()
+		if err == nil {
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				// Reset the connection instead of closing it gracefully.
+				_ = tcpConn.SetLinger(0)
+			}
+			_ = conn.Close()
+		}
+		return
+	}
+
+	if fault.RetryAfter != "" {
+		w.Header().Set("Retry-After", fault.RetryAfter)
+	}
+	http.Error(w, http.StatusText(fault.Status), fault.Status)
+}
+
+// lookup returns the records of the processing mode of a request, or nil if there are
+// none.
+func (s *Server) lookup(r *http.Request) *pmodeRecords {
+	return s.pmodes[pmodeKey{r.PathValue("machine"), r.PathValue("point"), r.PathValue("pmode")}]
+}
+
+// handleListWaves serves the listing of the waveforms of a processing mode.
+func (s *Server) handleListWaves(w http.ResponseWriter, r *http.Request) {
+	s.handleList(w, r, func(records *pmodeRecords) map[int64]storedRecord { return records.waves })
+}
+
+// handleListSpectra serves the listing of the spectra of a processing mode.
+func (s *Server) handleListSpectra(w http.ResponseWriter, r *http.Request) {
+	s.handleList(
+		w,
+		r,
+		func(records *pmodeRecords) map[int64]storedRecord { return records.spectra },
+	)
+}
+
+// handleList serves the listing of the records selected by kind, linking each record
+// like a T8 does.
+func (s *Server) handleList(
+	w http.ResponseWriter,
+	r *http.Request,
+	kind func(*pmodeRecords) map[int64]storedRecord,
+) {
+	s.mu.Lock()
+	records := s.lookup(r)
+	var timestamps []int64
+	if records != nil {
+		for timestamp := range kind(records) {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+	s.mu.Unlock()
+
+	if records == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	slices.Sort(timestamps)
+
+	type link struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	items := make([]link, len(timestamps))
+	base := "http://" + r.Host + r.URL.EscapedPath()
+	for i, timestamp := range timestamps {
+		items[i].Links.Self = base + strconv.FormatInt(timestamp, 10)
+	}
+
+	writeJSON(w, map[string]any{"_items": items})
+}
+
+// handleGetWave serves a waveform.
+func (s *Server) handleGetWave(w http.ResponseWriter, r *http.Request) {
+	s.handleGet(w, r, func(records *pmodeRecords) map[int64]storedRecord { return records.waves })
+}
+
+// handleGetSpectrum serves a spectrum.
+func (s *Server) handleGetSpectrum(w http.ResponseWriter, r *http.Request) {
+	s.handleGet(w, r, func(records *pmodeRecords) map[int64]storedRecord { return records.spectra })
+}
+
+// handleGet serves the record selected by kind and the timestamp of the request.
+func (s *Server) handleGet(
+	w http.ResponseWriter,
+	r *http.Request,
+	kind func(*pmodeRecords) map[int64]storedRecord,
+) {
+	timestamp, err := strconv.ParseInt(r.PathValue("timestamp"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	var record storedRecord
+	ok := false
+	if records := s.lookup(r); records != nil {
+		record, ok = kind(records)[timestamp]
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(record.body)
+}
+
+// handleTrend serves the trend of a processing mode, holding the RMS value of each of
+// its waveforms within the from and to query parameters.
+func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	for name, bound := range map[string]*int64{"from": &from, "to": &to} {
+		if value := r.URL.Query().Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s parameter", name), http.StatusBadRequest)
+				return
+			}
+			*bound = parsed
+		}
+	}
+
+	s.mu.Lock()
+	records := s.lookup(r)
+	timestamps := []int64{}
+	values := map[int64]float64{}
+	units := ""
+	if records != nil {
+		units = records.units
+		for timestamp, record := range records.waves {
+			if timestamp >= from && timestamp <= to {
+				timestamps = append(timestamps, timestamp)
+				values[timestamp] = record.rms
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if records == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	slices.Sort(timestamps)
+	rms := make([]float64, len(timestamps))
+	for i, timestamp := range timestamps {
+		rms[i] = values[timestamp]
+	}
+
+	data, factor := encode(rms)
+	writeJSON(w, map[string]any{
+		"timestamps": timestamps,
+		"params": []map[string]any{
+			{"name": TrendParameter, "units": units, "data": data, "factor": factor},
+		},
+	})
+}
+
+// handleMachines serves the machines that have records.
+func (s *Server) handleMachines(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var tags []string
+	for key := range s.pmodes {
+		tags = append(tags, key.machine)
+	}
+	s.mu.Unlock()
+
+	writeCollection(w, tags, func(tag string) map[string]any {
+		return map[string]any{"tag": tag, "name": tag}
+	})
+}
+
+// handlePoints serves the points of a machine that have records.
+func (s *Server) handlePoints(w http.ResponseWriter, r *http.Request) {
+	machine := r.PathValue("machine")
+
+	s.mu.Lock()
+	var tags []string
+	units := map[string]string{}
+	for key, records := range s.pmodes {
+		if key.machine == machine {
+			tags = append(tags, key.point)
+			if records.units != "" {
+				units[key.point] = records.units
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if tags == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeCollection(w, tags, func(tag string) map[string]any {
+		return map[string]any{"tag": tag, "name": tag, "units": units[tag]}
+	})
+}
+
+// handlePmodes serves the processing modes of a point that have records.
+func (s *Server) handlePmodes(w http.ResponseWriter, r *http.Request) {
+	machine, point := r.PathValue("machine"), r.PathValue("point")
+
+	s.mu.Lock()
+	var tags []string
+	items := map[string]map[string]any{}
+	for key, records := range s.pmodes {
+		if key.machine == machine && key.point == point {
+			tags = append(tags, key.pmode)
+			items[key.pmode] = map[string]any{
+				"tag":         key.pmode,
+				"name":        key.pmode,
+				"sample_rate": records.sampleRate,
+				"samples":     records.samples,
+				"units":       records.units,
+				"min_freq":    records.minFreq,
+				"max_freq":    records.maxFreq,
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if tags == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeCollection(w, tags, func(tag string) map[string]any { return items[tag] })
+}
+
+// writeCollection writes the items built by item for the given tags, sorted and without
+// duplicates, in the envelope of the T8 configuration listings.
+func writeCollection(w http.ResponseWriter, tags []string, item func(string) map[string]any) {
+	slices.Sort(tags)
+	tags = slices.Compact(tags)
+
+	items := make([]map[string]any, len(tags))
+	for i, tag := range tags {
+		items[i] = item(tag)
+	}
+
+	writeJSON(w, map[string]any{"_items": items})
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(mustMarshal(v))
+}
+
+// encode zint-encodes values like a T8 does, returning the encoded string and its scale
+// factor. The values must be finite.
+func encode(values []float64) (string, float64) {
+	raw, factor, err := codec.FloatToZint(values)
+	if err != nil {
+		panic(fmt.Sprintf("t8test: encoding values: %v", err))
+	}
+	return raw, factor
+}
+
+// mustMarshal encodes v, which must be encodable, as JSON.
+func mustMarshal(v any) []byte {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("t8test: encoding response: %v", err))
+	}
+	return body
+}
@@ -0,0 +1,321 @@
+package t8test_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/datafetcher"
+	"github.com/Daniel-C-R/t8-client-go/pkg/spectra"
+	"github.com/Daniel-C-R/t8-client-go/pkg/t8test"
+)
+
+// acquired is the acquisition time of the first record stored in the test servers.
+var acquired = time.Date(2019, 4, 10, 14, 48, 44, 0, time.UTC)
+
+// newPopulatedServer creates a server holding three waveforms of a 50 Hz tone, with
+// increasing amplitudes, and the spectrum of the first one.
+func newPopulatedServer(t *testing.T, opts ...t8test.Option) *t8test.Server {
+	t.Helper()
+
+	server := t8test.NewServer(opts...)
+	t.Cleanup(server.Close)
+
+	for i := range 3 {
+		waveform := t8test.SineWaveform(
+			2560,
+			2048,
+			t8test.Tone{Frequency: 50, Amplitude: float64(i + 1)},
+		)
+		waveform.Units = "g"
+		recordTime := acquired.Add(time.Duration(i) * time.Minute)
+		server.AddWaveform("machine", "point", "pmode", recordTime, waveform)
+
+		if i == 0 {
+			spectrum := spectra.SpectrumFromWaveform(waveform, 0, 1000)
+			server.AddSpectrum("machine", "point", "pmode", recordTime, spectrum)
+		}
+	}
+
+	return server
+}
+
+// newFetcher creates an HttpDataFetcher for server, retrying quickly.
+func newFetcher(
+	t *testing.T,
+	server *t8test.Server,
+	opts ...datafetcher.ClientOption,
+) datafetcher.HttpDataFetcher {
+	t.Helper()
+
+	policy := datafetcher.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = time.Millisecond
+
+	opts = append([]datafetcher.ClientOption{datafetcher.WithRetryPolicy(policy)}, opts...)
+	client, err := datafetcher.NewClient(server.URL, opts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return datafetcher.NewHttpDataFetcher(client)
+}
+
+// TestServerRecords tests fetching the stored waveforms and spectra.
+func TestServerRecords(t *testing.T) {
+	server := newPopulatedServer(t)
+	fetcher := newFetcher(t, server)
+
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	waveform, err := fetcher.GetWaveform(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := t8test.SineWaveform(2560, 2048, t8test.Tone{Frequency: 50, Amplitude: 1})
+	if len(waveform.Samples) != len(expected.Samples) {
+		t.Fatalf("expected %d samples, got %d", len(expected.Samples), len(waveform.Samples))
+	}
+	for i, sample := range waveform.Samples {
+		// Quantization to int16 keeps the error within half a step.
+		if math.Abs(sample-expected.Samples[i]) > 1.0/math.MaxInt16 {
+			t.Fatalf("expected sample %d to be %v, got %v", i, expected.Samples[i], sample)
+		}
+	}
+	if waveform.SampleRate != 2560 || waveform.Units != "g" || !waveform.Time.Equal(acquired) {
+		t.Errorf("unexpected waveform metadata %+v", waveform.Metadata)
+	}
+
+	spectrum, err := fetcher.GetSpectrum(params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	peak := 0
+	for i, magnitude := range spectrum.Magnitudes {
+		if magnitude > spectrum.Magnitudes[peak] {
+			peak = i
+		}
+	}
+	if math.Abs(spectrum.Frequencies[peak]-50) > 2560.0/2048 {
+		t.Errorf("expected a peak at 50 Hz, got %v Hz", spectrum.Frequencies[peak])
+	}
+
+	missing := datafetcher.NewPmodeUrlTimeParams("machine", "point", "other", "2019-04-10T14:48:44")
+	if _, err := fetcher.GetWaveform(missing); !errors.Is(err, datafetcher.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestServerListing tests listing and downloading the stored records.
+func TestServerListing(t *testing.T) {
+	server := newPopulatedServer(t)
+	fetcher := newFetcher(t, server)
+
+	results, err := datafetcher.DownloadRange(
+		context.Background(),
+		fetcher,
+		datafetcher.NewPmodeUrlParams("machine", "point", "pmode"),
+		datafetcher.TimeRange{From: acquired.Add(time.Minute)},
+		datafetcher.BatchOptions{Waveforms: true, Spectra: true},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	count := 0
+	for result := range results {
+		if result.Err != nil {
+			t.Errorf("expected no error, got %v", result.Err)
+		}
+		if result.Kind != datafetcher.WaveformRecord {
+			t.Errorf("expected only waveforms, got %s", result.Kind)
+		}
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 waveforms, got %d", count)
+	}
+}
+
+// TestServerTrendAndDiscovery tests the trends and configuration derived from the records.
+func TestServerTrendAndDiscovery(t *testing.T) {
+	server := newPopulatedServer(t)
+	fetcher := newFetcher(t, server)
+
+	trend, err := fetcher.GetTrend(
+		context.Background(),
+		datafetcher.NewPmodeUrlParams("machine", "point", "pmode"),
+		datafetcher.TimeRange{},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rms, ok := trend.Parameter(t8test.TrendParameter)
+	if !ok || len(rms.Values) != 3 || rms.Units != "g" {
+		t.Fatalf("unexpected trend %+v", trend)
+	}
+	for i, value := range rms.Values {
+		expected := float64(i+1) / math.Sqrt2
+		if math.Abs(value-expected) > 1e-3 {
+			t.Errorf("expected RMS %v, got %v", expected, value)
+		}
+	}
+
+	machines, err := fetcher.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(machines) != 1 || len(machines[0].Points) != 1 ||
+		len(machines[0].Points[0].Pmodes) != 1 {
+		t.Fatalf("unexpected configuration %+v", machines)
+	}
+
+	pmode := machines[0].Points[0].Pmodes[0]
+	expectedPmode := datafetcher.Pmode{
+		Tag:        "pmode",
+		Name:       "pmode",
+		SampleRate: 2560,
+		Samples:    2048,
+		Units:      "g",
+		MinFreq:    0,
+		MaxFreq:    1000,
+	}
+	if pmode != expectedPmode {
+		t.Errorf("expected processing mode %+v, got %+v", expectedPmode, pmode)
+	}
+}
+
+// TestServerBasicAuth tests that the credentials are checked.
+func TestServerBasicAuth(t *testing.T) {
+	server := newPopulatedServer(t, t8test.WithBasicAuth("user", "password"))
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	fetcher := newFetcher(t, server, datafetcher.WithCredentials("user", "wrong"))
+	if _, err := fetcher.GetWaveform(params); !errors.Is(err, datafetcher.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+
+	fetcher = newFetcher(t, server, datafetcher.WithCredentials("user", "password"))
+	if _, err := fetcher.GetWaveform(params); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestServerFaults tests that injected faults are retried by the client.
+func TestServerFaults(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fault t8test.Fault
+	}{
+		{
+			name:  "Service Unavailable",
+			fault: t8test.Fault{Status: http.StatusServiceUnavailable, RetryAfter: "0", Count: 2},
+		},
+		{
+			name:  "Connection Reset",
+			fault: t8test.Fault{PathPrefix: "/waves/", Count: 2},
+		},
+	}
+
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newPopulatedServer(t)
+			fetcher := newFetcher(t, server)
+
+			server.InjectFault(tc.fault)
+
+			if _, err := fetcher.GetWaveform(params); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+
+			if count := server.RequestCount(); count != 3 {
+				t.Errorf("expected 3 requests, got %d", count)
+			}
+		})
+	}
+}
+
+// TestServerPermanentFault tests faults that last until they are cleared.
+func TestServerPermanentFault(t *testing.T) {
+	server := newPopulatedServer(t)
+	fetcher := newFetcher(t, server)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	server.InjectFault(
+		t8test.Fault{PathPrefix: "/spectra/", Status: http.StatusInternalServerError},
+	)
+
+	var statusErr *datafetcher.StatusError
+	if _, err := fetcher.GetSpectrum(params); !errors.As(err, &statusErr) ||
+		statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %v", err)
+	}
+
+	if _, err := fetcher.GetWaveform(params); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	server.ClearFaults()
+
+	if _, err := fetcher.GetSpectrum(params); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestServerLatency tests that the latency makes requests time out.
+func TestServerLatency(t *testing.T) {
+	server := newPopulatedServer(t, t8test.WithLatency(time.Second))
+	fetcher := newFetcher(t, server)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := fetcher.GetWaveformContext(ctx, params); !errors.Is(
+		err,
+		context.DeadlineExceeded,
+	) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	server.SetLatency(0)
+
+	if _, err := fetcher.GetWaveform(params); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestServerMaxConcurrentRequests tests that the requests handled at the same time are
+// counted while they are delayed.
+func TestServerMaxConcurrentRequests(t *testing.T) {
+	server := newPopulatedServer(t, t8test.WithLatency(50*time.Millisecond))
+	fetcher := newFetcher(t, server)
+	params := datafetcher.NewPmodeUrlTimeParams("machine", "point", "pmode", "2019-04-10T14:48:44")
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetcher.GetWaveform(params); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := server.MaxConcurrentRequests(); got != 3 {
+		t.Errorf("expected 3 concurrent requests, got %d", got)
+	}
+}
@@ -0,0 +1,39 @@
+package t8test
+
+import (
+	"math"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// Tone is a sinusoidal component of a synthetic signal.
+type Tone struct {
+	// Frequency is the frequency of the tone, in Hz.
+	Frequency float64
+	// Amplitude is the peak amplitude of the tone.
+	Amplitude float64
+	// Phase is the phase of the tone at the first sample, in radians.
+	Phase float64
+}
+
+// SineWaveform creates a synthetic waveform adding up the given tones.
+//
+// Parameters:
+//   - sampleRate: The sample rate of the waveform, in Hz.
+//   - samples: The number of samples of the waveform.
+//   - tones: The components of the signal. Without tones, the waveform is all zeros.
+//
+// Returns:
+//
+//	A waveforms.Waveform holding the samples and the sample rate, without metadata.
+func SineWaveform(sampleRate float64, samples int, tones ...Tone) waveforms.Waveform {
+	values := make([]float64, samples)
+	for i := range values {
+		t := float64(i) / sampleRate
+		for _, tone := range tones {
+			values[i] += tone.Amplitude * math.Sin(2*math.Pi*tone.Frequency*t+tone.Phase)
+		}
+	}
+
+	return waveforms.Waveform{Samples: values, SampleRate: sampleRate}
+}
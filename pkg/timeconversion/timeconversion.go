@@ -0,0 +1,154 @@
+// Package timeconversion parses and formats the acquisition times T8 records are
+// identified by.
+package timeconversion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsoLayout is the layout of the ISO 8601 date and time strings, without time zone,
+// used to identify T8 records.
+const IsoLayout = "2006-01-02T15:04:05"
+
+// nowKeyword is the keyword ParseTime reads as the current time, optionally followed by
+// a signed duration.
+const nowKeyword = "now"
+
+// maxSecondsDigits and millisecondsDigits are the number of digits of the Unix
+// timestamps ParseTime reads as seconds, up to 2286, and as milliseconds, from 2001 to
+// 2286.
+const (
+	maxSecondsDigits   = 10
+	millisecondsDigits = 13
+)
+
+// IsoStringToTimestamp converts an ISO 8601 date and time string without time zone, in
+// the IsoLayout format, into a Unix timestamp. The time is assumed to be in UTC; use
+// ParseTime to accept time zone offsets and other formats.
+//
+// Parameters:
+//   - isoString: A string representing the date and time in the IsoLayout format.
+//
+// Returns:
+//   - int64: The Unix timestamp corresponding to the input date and time.
+//   - error: An error if the input string is not in the IsoLayout format.
+func IsoStringToTimestamp(isoString string) (int64, error) {
+	t, err := time.Parse(IsoLayout, isoString)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// TimeToIsoString formats t, converted to UTC, as an ISO 8601 string accepted by
+// IsoStringToTimestamp.
+//
+// Parameters:
+//   - t: The time to format.
+//
+// Returns:
+//   - string: The UTC date and time of t in ISO 8601 format, without time zone.
+func TimeToIsoString(t time.Time) string {
+	return t.UTC().Format(IsoLayout)
+}
+
+// ParseTime parses a point in time written in any of the following forms:
+//   - RFC 3339, with a time zone offset or "Z", e.g. "2019-04-11T20:25:54+02:00".
+//   - ISO 8601 without time zone, in the IsoLayout format, read in loc.
+//   - A Unix timestamp in seconds of up to 10 digits, e.g. "1555007154", or in
+//     milliseconds of 13 digits, e.g. "1555007154000", covering 1970 to 2286. Other
+//     lengths, and negative timestamps, are rejected rather than read as implausible
+//     dates.
+//   - "now", optionally followed by a signed duration accepted by time.ParseDuration,
+//     e.g. "now-2h" or "now+30m", relative to now.
+//
+// Parameters:
+//   - s: The string to parse.
+//   - loc: The location of times without time zone, or nil for UTC.
+//   - now: The time relative expressions are resolved against.
+//
+// Returns:
+//   - time.Time: The parsed time.
+//   - error: An error if s is not in any of the accepted forms.
+func ParseTime(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if offset, ok := strings.CutPrefix(s, nowKeyword); ok {
+		if offset == "" {
+			return now, nil
+		}
+		if offset[0] != '+' && offset[0] != '-' {
+			return time.Time{}, fmt.Errorf("invalid relative time %q", s)
+		}
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w", s, err)
+		}
+		return now.Add(d), nil
+	}
+
+	if isDigits(strings.TrimPrefix(s, "-")) {
+		return parseTimestamp(s, loc)
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(IsoLayout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"invalid time %q: expected RFC 3339, %s, a Unix timestamp or now[+-]duration",
+			s,
+			IsoLayout,
+		)
+	}
+	return t, nil
+}
+
+// parseTimestamp parses s, a string of digits optionally preceded by a minus sign, as a
+// Unix timestamp in seconds or milliseconds according to its number of digits, as
+// described by ParseTime.
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	if strings.HasPrefix(s, "-") {
+		return time.Time{}, fmt.Errorf("invalid Unix timestamp %q: times before 1970", s)
+	}
+
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Unix timestamp %q: %w", s, err)
+	}
+
+	switch {
+	case len(s) <= maxSecondsDigits:
+		return time.Unix(value, 0).In(loc), nil
+	case len(s) == millisecondsDigits:
+		return time.UnixMilli(value).In(loc), nil
+	default:
+		return time.Time{}, fmt.Errorf(
+			"invalid Unix timestamp %q: expected up to %d digits for seconds or %d for milliseconds",
+			s,
+			maxSecondsDigits,
+			millisecondsDigits,
+		)
+	}
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
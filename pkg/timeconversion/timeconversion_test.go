@@ -0,0 +1,200 @@
+package timeconversion_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/timeconversion"
+)
+
+func TestIsoStringToTimestamp(t *testing.T) {
+	test := []struct {
+		name     string
+		input    string
+		expected int64
+		mustFail bool
+	}{
+		{
+			name:     "Valid ISO String",
+			input:    "2019-04-11T18:25:54",
+			expected: 1555007154,
+			mustFail: false,
+		},
+		{
+			name:     "Invalid ISO String",
+			input:    "2019-04-11T18:25:54Z",
+			expected: 0,
+			mustFail: true,
+		},
+	}
+
+	for _, tc := range test {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := timeconversion.IsoStringToTimestamp(tc.input)
+			if tc.mustFail {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+				}
+				if result != tc.expected {
+					t.Errorf("Expected %d but got %d", tc.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeToIsoString(t *testing.T) {
+	test := []struct {
+		name     string
+		input    time.Time
+		expected string
+	}{
+		{
+			name:     "UTC Time",
+			input:    time.Unix(1555007154, 0).UTC(),
+			expected: "2019-04-11T18:25:54",
+		},
+		{
+			name:     "Time With Offset",
+			input:    time.Unix(1555007154, 0).In(time.FixedZone("CEST", 2*60*60)),
+			expected: "2019-04-11T18:25:54",
+		},
+	}
+
+	for _, tc := range test {
+		t.Run(tc.name, func(t *testing.T) {
+			result := timeconversion.TimeToIsoString(tc.input)
+			if result != tc.expected {
+				t.Errorf("Expected %s but got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	madrid := time.FixedZone("CEST", 2*60*60)
+	now := time.Date(2019, 4, 11, 18, 25, 54, 0, time.UTC)
+
+	test := []struct {
+		name     string
+		input    string
+		loc      *time.Location
+		expected time.Time
+		mustFail bool
+	}{
+		{
+			name:     "Naive ISO String In UTC",
+			input:    "2019-04-11T18:25:54",
+			expected: now,
+		},
+		{
+			name:     "Naive ISO String In Location",
+			input:    "2019-04-11T20:25:54",
+			loc:      madrid,
+			expected: now,
+		},
+		{
+			name:     "RFC3339 With Z",
+			input:    "2019-04-11T18:25:54Z",
+			loc:      madrid,
+			expected: now,
+		},
+		{
+			name:     "RFC3339 With Offset",
+			input:    "2019-04-11T13:25:54-05:00",
+			expected: now,
+		},
+		{
+			name:     "Unix Timestamp",
+			input:    "1555007154",
+			expected: now,
+		},
+		{
+			name:     "Unix Timestamp In Milliseconds",
+			input:    "1555007154250",
+			expected: now.Add(250 * time.Millisecond),
+		},
+		{
+			name:     "Unix Epoch",
+			input:    "0",
+			expected: time.Unix(0, 0),
+		},
+		{
+			name:     "Latest Unix Timestamp In Seconds",
+			input:    "9999999999",
+			expected: time.Unix(9999999999, 0),
+		},
+		{
+			name:     "Unix Timestamp Of 11 Digits",
+			input:    "15550071542",
+			mustFail: true,
+		},
+		{
+			name:     "Unix Timestamp In Microseconds",
+			input:    "1555007154000000",
+			mustFail: true,
+		},
+		{
+			name:     "Negative Unix Timestamp",
+			input:    "-1555007154",
+			mustFail: true,
+		},
+		{
+			name:     "Now",
+			input:    "now",
+			expected: now,
+		},
+		{
+			name:     "Now Minus Duration",
+			input:    " now-2h30m ",
+			expected: now.Add(-150 * time.Minute),
+		},
+		{
+			name:     "Now Plus Duration",
+			input:    "now+15m",
+			expected: now.Add(15 * time.Minute),
+		},
+		{
+			name:     "Unsigned Relative Time",
+			input:    "now2h",
+			mustFail: true,
+		},
+		{
+			name:     "Invalid Duration",
+			input:    "now-2x",
+			mustFail: true,
+		},
+		{
+			name:     "Invalid String",
+			input:    "yesterday",
+			mustFail: true,
+		},
+		{
+			name:     "Empty String",
+			input:    "",
+			mustFail: true,
+		},
+	}
+
+	for _, tc := range test {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := timeconversion.ParseTime(tc.input, tc.loc, now)
+			if tc.mustFail {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+				}
+				if !result.Equal(tc.expected) {
+					t.Errorf("Expected %v but got %v", tc.expected, result)
+				}
+			}
+		})
+	}
+}
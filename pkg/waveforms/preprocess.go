@@ -0,0 +1,134 @@
+package waveforms
+
+import (
+	"slices"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// Detrend selects the trend removed from the samples of a waveform before windowing.
+type Detrend int
+
+const (
+	// NoDetrend leaves the samples as they are.
+	NoDetrend Detrend = iota
+	// RemoveMean subtracts the mean of the samples, i.e. their DC component.
+	RemoveMean
+	// RemoveLinear subtracts the least-squares straight line fitted to the samples, which
+	// removes both their DC component and any linear drift.
+	RemoveLinear
+)
+
+// Padding selects how a waveform is extended with zeros after windowing.
+type Padding int
+
+const (
+	// NoPadding keeps the number of samples.
+	NoPadding Padding = iota
+	// PadToPowerOfTwo appends zeros up to the next power of 2, as ZeroPadding does.
+	PadToPowerOfTwo
+)
+
+// Window is a window function: it multiplies seq in place by its weights and returns
+// it, like Hann and the functions of gonum.org/v1/gonum/dsp/window.
+type Window func(seq []float64) []float64
+
+// PreprocessOptions are the steps applied by Preprocess, in the order of the fields.
+type PreprocessOptions struct {
+	// Detrend is the trend removed from the samples.
+	Detrend Detrend
+	// Window is the window function applied to the samples, or nil for none. It is not
+	// applied to waveforms of less than 2 samples.
+	Window Window
+	// Padding is how the windowed samples are extended with zeros.
+	Padding Padding
+}
+
+// DefaultPreprocessOptions returns the options used to compute spectra from waveforms:
+// a Hann window to reduce spectral leakage, followed by zero-padding to the next power
+// of 2.
+func DefaultPreprocessOptions() PreprocessOptions {
+	return PreprocessOptions{Window: Hann, Padding: PadToPowerOfTwo}
+}
+
+// Preprocess prepares the waveform for spectral analysis: it removes the trend of its
+// samples, applies a window function and pads them with zeros, as set by opts. Padding
+// is done after windowing, so that the window spans the acquired samples only.
+//
+// Parameters:
+//   - opts: The preprocessing steps, e.g. DefaultPreprocessOptions().
+//
+// Returns:
+//
+//	A new Waveform with the preprocessed samples, the sample rate and the metadata of the
+//	waveform. The samples of the waveform are not modified, and a waveform without
+//	samples yields a Waveform without samples.
+func (waveform Waveform) Preprocess(opts PreprocessOptions) Waveform {
+	samples := slices.Clone(waveform.Samples)
+
+	switch opts.Detrend {
+	case RemoveMean:
+		removeMean(samples)
+	case RemoveLinear:
+		removeLinear(samples)
+	}
+
+	if opts.Window != nil && len(samples) > 1 {
+		samples = opts.Window(samples)
+	}
+
+	if opts.Padding == PadToPowerOfTwo {
+		padded := make([]float64, nextPowerOfTwo(len(samples)))
+		copy(padded, samples)
+		samples = padded
+	}
+
+	waveform.Samples = samples
+	return waveform
+}
+
+// removeMean subtracts the mean of samples from each of them.
+func removeMean(samples []float64) {
+	if len(samples) == 0 {
+		return
+	}
+	floats.AddConst(-floats.Sum(samples)/float64(len(samples)), samples)
+}
+
+// removeLinear subtracts the least-squares straight line fitted to samples, taken at
+// evenly spaced times, from each of them.
+func removeLinear(samples []float64) {
+	n := len(samples)
+	if n < 2 {
+		removeMean(samples)
+		return
+	}
+
+	meanX := float64(n-1) / 2
+	meanY := floats.Sum(samples) / float64(n)
+
+	var covariance, variance float64
+	for i, y := range samples {
+		dx := float64(i) - meanX
+		covariance += dx * (y - meanY)
+		variance += dx * dx
+	}
+	slope := covariance / variance
+
+	for i := range samples {
+		samples[i] -= meanY + slope*(float64(i)-meanX)
+	}
+}
+
+// nextPowerOfTwo returns the smallest power of 2 greater than or equal to n, or 0 if n
+// is not positive, so that padding no samples yields no samples.
+func nextPowerOfTwo(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	length := 1
+	for length < n {
+		length *= 2
+	}
+	return length
+}
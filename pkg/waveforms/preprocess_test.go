@@ -0,0 +1,153 @@
+package waveforms_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+	"gonum.org/v1/gonum/dsp/window"
+	"gonum.org/v1/gonum/floats"
+)
+
+// TestPreprocess tests the output of every preprocessing step.
+func TestPreprocess(t *testing.T) {
+	testCases := []struct {
+		name     string
+		samples  []float64
+		opts     waveforms.PreprocessOptions
+		expected []float64
+	}{
+		{
+			name:     "No Steps",
+			samples:  []float64{1, 2, 3},
+			opts:     waveforms.PreprocessOptions{},
+			expected: []float64{1, 2, 3},
+		},
+		{
+			name:     "Remove Mean",
+			samples:  []float64{1, 2, 3, 4, 5},
+			opts:     waveforms.PreprocessOptions{Detrend: waveforms.RemoveMean},
+			expected: []float64{-2, -1, 0, 1, 2},
+		},
+		{
+			name:     "Remove Linear Trend",
+			samples:  []float64{0, 2, 1, 3},
+			opts:     waveforms.PreprocessOptions{Detrend: waveforms.RemoveLinear},
+			expected: []float64{-0.3, 0.9, -0.9, 0.3},
+		},
+		{
+			name:     "Remove Linear Trend Of Single Sample",
+			samples:  []float64{4},
+			opts:     waveforms.PreprocessOptions{Detrend: waveforms.RemoveLinear},
+			expected: []float64{0},
+		},
+		{
+			name:     "Hann Window",
+			samples:  []float64{1, 1, 1, 1, 1},
+			opts:     waveforms.PreprocessOptions{Window: window.Hann},
+			expected: []float64{0, 0.5, 1, 0.5, 0},
+		},
+		{
+			name:     "Window Of Single Sample",
+			samples:  []float64{3},
+			opts:     waveforms.PreprocessOptions{Window: window.Hann},
+			expected: []float64{3},
+		},
+		{
+			name:     "Padding",
+			samples:  []float64{1, 2, 3, 4, 5},
+			opts:     waveforms.PreprocessOptions{Padding: waveforms.PadToPowerOfTwo},
+			expected: []float64{1, 2, 3, 4, 5, 0, 0, 0},
+		},
+		{
+			name:     "Padding Of Power Of Two",
+			samples:  []float64{1, 2, 3, 4},
+			opts:     waveforms.PreprocessOptions{Padding: waveforms.PadToPowerOfTwo},
+			expected: []float64{1, 2, 3, 4},
+		},
+		{
+			name:     "Default Options Window Before Padding",
+			samples:  []float64{2, 2, 2, 2, 2},
+			opts:     waveforms.DefaultPreprocessOptions(),
+			expected: []float64{0, 1, 2, 1, 0, 0, 0, 0},
+		},
+		{
+			name:    "All Steps",
+			samples: []float64{1, 2, 3, 4, 5},
+			opts: waveforms.PreprocessOptions{
+				Detrend: waveforms.RemoveMean,
+				Window:  window.Hann,
+				Padding: waveforms.PadToPowerOfTwo,
+			},
+			expected: []float64{0, -0.5, 0, 0.5, 0, 0, 0, 0},
+		},
+		{
+			name:     "Empty",
+			samples:  []float64{},
+			opts:     waveforms.DefaultPreprocessOptions(),
+			expected: []float64{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			original := slices.Clone(tc.samples)
+			waveform := waveforms.Waveform{Samples: tc.samples, SampleRate: 2560}
+
+			result := waveform.Preprocess(tc.opts)
+
+			if !floats.EqualApprox(result.Samples, tc.expected, 1e-12) {
+				t.Errorf("expected samples %v, got %v", tc.expected, result.Samples)
+			}
+			if !slices.Equal(waveform.Samples, original) {
+				t.Errorf(
+					"expected original samples %v to be kept, got %v",
+					original,
+					waveform.Samples,
+				)
+			}
+			if result.SampleRate != waveform.SampleRate {
+				t.Errorf("expected sample rate %v, got %v", waveform.SampleRate, result.SampleRate)
+			}
+		})
+	}
+}
+
+// TestPreprocessKeepsMetadata tests that the preprocessed waveform carries the metadata of
+// the original one.
+func TestPreprocessKeepsMetadata(t *testing.T) {
+	waveform := waveforms.Waveform{
+		Metadata: metadata.Metadata{Machine: "machine", Point: "point", Pmode: "pmode"},
+		Samples:  []float64{1, 2, 3},
+	}
+
+	result := waveform.Preprocess(waveforms.DefaultPreprocessOptions())
+
+	if result.Label() != waveform.Label() {
+		t.Errorf("expected label %q, got %q", waveform.Label(), result.Label())
+	}
+}
+
+// TestZeroPadding tests that ZeroPadding pads the samples of the waveform in place.
+func TestZeroPadding(t *testing.T) {
+	waveform := waveforms.Waveform{Samples: []float64{1, 2, 3}}
+
+	waveform.ZeroPadding()
+
+	expected := []float64{1, 2, 3, 0}
+	if !slices.Equal(waveform.Samples, expected) {
+		t.Errorf("expected samples %v, got %v", expected, waveform.Samples)
+	}
+}
+
+// TestZeroPaddingEmpty tests that padding a waveform without samples adds none.
+func TestZeroPaddingEmpty(t *testing.T) {
+	waveform := waveforms.Waveform{}
+
+	waveform.ZeroPadding()
+
+	if len(waveform.Samples) != 0 {
+		t.Errorf("expected no samples, got %v", waveform.Samples)
+	}
+}
@@ -1,12 +1,15 @@
 package waveforms
 
 import (
-	"gonum.org/v1/gonum/dsp/window"
+	"github.com/Daniel-C-R/t8-client-go/pkg/metadata"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 )
 
+// Waveform holds the samples of a waveform, its sample rate, and the metadata of the
+// record it was obtained from.
 type Waveform struct {
+	metadata.Metadata
 	Samples    []float64
 	SampleRate float64
 }
@@ -16,32 +19,14 @@ type Waveform struct {
 // achieved by creating a new slice with the padded length and copying the
 // original samples into it, leaving the additional elements initialized to zero.
 func (waveform *Waveform) ZeroPadding() {
-	// Find the next power of 2 greater than or equal to the length of the samples
-	n := len(waveform.Samples)
-	paddedLength := 1
-	for paddedLength < n {
-		paddedLength *= 2
-	}
-
 	// Create a new slice with the padded length
-	paddedWaveform := make([]float64, paddedLength)
+	paddedWaveform := make([]float64, nextPowerOfTwo(len(waveform.Samples)))
 	copy(paddedWaveform, waveform.Samples)
 
 	// Update the waveform's samples to the padded slice
 	waveform.Samples = paddedWaveform
 }
 
-// Preprocess applies preprocessing steps to the waveform.
-// It performs zero-padding to extend the waveform and applies
-// a Hanning window function to the waveform samples to reduce
-// spectral leakage during analysis.
-func (waveform Waveform) Preprocess() {
-	// Apply zero-padding to the waveform
-	waveform.ZeroPadding()
-	// Apply a Hanning window to the waveform
-	window.Hann(waveform.Samples)
-}
-
 // Plot genera una gráfica de la forma de onda actual.
 func (waveform Waveform) Plot() (*plot.Plot, error) {
 	p := plot.New()
@@ -59,8 +44,14 @@ func (waveform Waveform) Plot() (*plot.Plot, error) {
 
 	p.Add(line)
 	p.Title.Text = "Waveform"
+	if label := waveform.Label(); label != "" {
+		p.Title.Text += " " + label
+	}
 	p.X.Label.Text = "Time (s)"
 	p.Y.Label.Text = "Amplitude"
+	if waveform.Units != "" {
+		p.Y.Label.Text += " (" + waveform.Units + ")"
+	}
 
 	return p, nil
 }
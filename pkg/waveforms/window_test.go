@@ -0,0 +1,152 @@
+package waveforms_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Daniel-C-R/t8-client-go/pkg/waveforms"
+)
+
+// TestWindowCorrections tests the coherent gain and equivalent noise bandwidth of every
+// window function against their published values.
+func TestWindowCorrections(t *testing.T) {
+	testCases := []struct {
+		name         string
+		window       waveforms.Window
+		coherentGain float64
+		enbw         float64
+	}{
+		{name: "None", window: nil, coherentGain: 1, enbw: 1},
+		{name: "Rectangular", window: waveforms.Rectangular, coherentGain: 1, enbw: 1},
+		{name: "Hann", window: waveforms.Hann, coherentGain: 0.5, enbw: 1.5},
+		{name: "Hamming", window: waveforms.Hamming, coherentGain: 0.54, enbw: 1.363},
+		{name: "Flat Top", window: waveforms.FlatTop, coherentGain: 0.2156, enbw: 3.770},
+		{
+			name:         "Blackman-Harris",
+			window:       waveforms.BlackmanHarris,
+			coherentGain: 0.3587,
+			enbw:         2.004,
+		},
+		{name: "Kaiser Beta 0", window: waveforms.Kaiser(0), coherentGain: 1, enbw: 1},
+		{
+			name:         "Kaiser Beta 3 Pi",
+			window:       waveforms.Kaiser(3 * math.Pi),
+			coherentGain: 0.40,
+			enbw:         1.80,
+		},
+	}
+
+	const samples = 4096
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := waveforms.CoherentGain(tc.window, samples); math.Abs(
+				got-tc.coherentGain,
+			) > 5e-3 {
+				t.Errorf("expected coherent gain %v, got %v", tc.coherentGain, got)
+			}
+			if got := waveforms.EquivalentNoiseBandwidth(tc.window, samples); math.Abs(
+				got-tc.enbw,
+			) > 1e-2 {
+				t.Errorf("expected equivalent noise bandwidth %v, got %v", tc.enbw, got)
+			}
+		})
+	}
+}
+
+// TestKaiser tests the weights of the Kaiser window.
+func TestKaiser(t *testing.T) {
+	seq := waveforms.Kaiser(2)([]float64{1, 1, 1})
+
+	// I0(0) / I0(2) at the ends, and 1 at the centre.
+	expected := []float64{1 / 2.2795853023360673, 1, 1 / 2.2795853023360673}
+	for i := range expected {
+		if math.Abs(seq[i]-expected[i]) > 1e-12 {
+			t.Errorf("expected weights %v, got %v", expected, seq)
+			break
+		}
+	}
+}
+
+// besselI0 returns the modified Bessel function of the first kind of order 0 at x, from
+// its power series, which does not overflow up to x of about 700.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-17; k++ {
+		term *= x * x / float64(4*k*k)
+		sum += term
+	}
+	return sum
+}
+
+// TestKaiserLargeBeta tests that the weights of the Kaiser window match the definition
+// on both sides of the switch to the asymptotic expansion of I0, and stay finite for
+// betas whose I0 overflows.
+func TestKaiserLargeBeta(t *testing.T) {
+	testCases := []struct {
+		name string
+		beta float64
+	}{
+		{name: "Beta 20", beta: 20},
+		{name: "Beta 40", beta: 40},
+		{name: "Beta 700", beta: 700},
+		{name: "Beta 1000", beta: 1000},
+		{name: "Beta 1e6", beta: 1e6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seq := waveforms.Kaiser(tc.beta)([]float64{1, 1, 1, 1, 1})
+
+			for i, w := range seq {
+				if math.IsNaN(w) || w < 0 || w > 1 {
+					t.Fatalf("expected weights between 0 and 1, got %v at %d", w, i)
+				}
+			}
+			if seq[2] != 1 {
+				t.Errorf("expected a centre weight of 1, got %v", seq[2])
+			}
+
+			if tc.beta > 700 {
+				return
+			}
+			expected := besselI0(tc.beta*math.Sqrt(0.75)) / besselI0(tc.beta)
+			if math.Abs(seq[1]-expected) > 1e-12*expected {
+				t.Errorf("expected weight %v, got %v", expected, seq[1])
+			}
+		})
+	}
+}
+
+// TestParseWindow tests parsing window names.
+func TestParseWindow(t *testing.T) {
+	testCases := []struct {
+		name    string
+		beta    float64
+		isError bool
+	}{
+		{name: "rectangular"},
+		{name: "hann"},
+		{name: "hamming"},
+		{name: "flattop"},
+		{name: "blackmanharris"},
+		{name: "kaiser", beta: 8.6},
+		{name: "kaiser", beta: -1, isError: true},
+		{name: "kaiser", beta: math.Inf(1), isError: true},
+		{name: "triangular", isError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			window, err := waveforms.ParseWindow(tc.name, tc.beta)
+			if tc.isError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil || window == nil {
+				t.Errorf("expected a window, got error %v", err)
+			}
+		})
+	}
+}